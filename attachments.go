@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/blobstore"
+	"github.com/keithcrooks/chirpy/internal/database"
+	"github.com/keithcrooks/chirpy/internal/media"
+)
+
+const (
+	maxChirpMedia   = 4
+	maxUploadMemory = 8 << 20
+)
+
+// Media is an image attached to a Chirp. The URLs are relative to the API's
+// own origin.
+type Media struct {
+	ID           uuid.UUID `json:"id"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+}
+
+func (cfg *apiConfig) handlerGetMedia(w http.ResponseWriter, req *http.Request) {
+	cfg.serveMedia(w, req, mediaKey)
+}
+
+func (cfg *apiConfig) handlerGetMediaThumbnail(w http.ResponseWriter, req *http.Request) {
+	cfg.serveMedia(w, req, thumbnailKey)
+}
+
+// serveMedia writes the blob that key names for the attachment in the
+// {mediaID} path value. Attachments never change once stored, so clients may
+// cache them indefinitely.
+func (cfg *apiConfig) serveMedia(w http.ResponseWriter, req *http.Request, key func(uuid.UUID) string) {
+	mediaID, err := uuid.Parse(req.PathValue("mediaID"))
+	if err != nil {
+		logger(req.Context()).Warn("Error parsing media ID", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid media ID")
+		return
+	}
+
+	attachment, err := cfg.db.GetChirpAttachment(req.Context(), mediaID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting attachment from DB", "error", err)
+
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusNotFound, "Media not found")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Unknown error getting media")
+		}
+		return
+	}
+
+	blob, err := cfg.blobs.Get(req.Context(), key(attachment.ID))
+	if err != nil {
+		logger(req.Context()).Error("Error getting media blob", "error", err)
+
+		switch {
+		case errors.Is(err, blobstore.ErrNotFound):
+			respondWithError(w, http.StatusNotFound, "Media not found")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Unknown error getting media")
+		}
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, blob); err != nil {
+		logger(req.Context()).Error("Error writing media", "error", err)
+	}
+}
+
+func isMultipartRequest(req *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/form-data"
+}
+
+// parseChirpUpload reads a Chirp sent as a multipart form, with the Chirp in
+// the body, reply_to_id and quote_of_id fields and up to maxChirpMedia images
+// in media fields.
+func parseChirpUpload(w http.ResponseWriter, req *http.Request) (Chirp, []media.Image, error) {
+	req.Body = http.MaxBytesReader(w, req.Body, maxChirpMedia*media.MaxImageBytes+maxUploadMemory)
+	if err := req.ParseMultipartForm(maxUploadMemory); err != nil {
+		logger(req.Context()).Warn("Error parsing multipart form", "error", err)
+		return Chirp{}, nil, errors.New("Could not read Chirp")
+	}
+
+	replyToID, err := parseOptionalUUID(req.FormValue("reply_to_id"))
+	if err != nil {
+		return Chirp{}, nil, errors.New("Could not read Chirp")
+	}
+
+	quoteOfID, err := parseOptionalUUID(req.FormValue("quote_of_id"))
+	if err != nil {
+		return Chirp{}, nil, errors.New("Could not read Chirp")
+	}
+
+	chirp := Chirp{Body: req.FormValue("body"), ReplyToID: replyToID, QuoteOfID: quoteOfID}
+	if len(chirp.Body) > maxChirpLength {
+		return Chirp{}, nil, errors.New("Chirp is too long")
+	}
+
+	files := req.MultipartForm.File["media"]
+	if len(files) > maxChirpMedia {
+		return Chirp{}, nil, errors.New("A Chirp can have at most 4 images")
+	}
+
+	images := make([]media.Image, 0, len(files))
+	for _, fh := range files {
+		if fh.Size > media.MaxImageBytes {
+			return Chirp{}, nil, errors.New("Image is too large")
+		}
+
+		f, err := fh.Open()
+		if err != nil {
+			return Chirp{}, nil, err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return Chirp{}, nil, err
+		}
+
+		img, err := media.Process(data)
+		if err != nil {
+			if errors.Is(err, media.ErrTooLarge) {
+				return Chirp{}, nil, errors.New("Image is too large")
+			}
+			return Chirp{}, nil, errors.New("Images must be JPEG, PNG or GIF")
+		}
+
+		images = append(images, img)
+	}
+
+	return chirp, images, nil
+}
+
+// storeImages writes images and their thumbnails to the blob store, returning
+// the attachment ID each was stored under. Nothing is left behind on error.
+func (cfg *apiConfig) storeImages(ctx context.Context, images []media.Image) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(images))
+
+	for _, img := range images {
+		id := uuid.New()
+		ids = append(ids, id)
+
+		if err := cfg.blobs.Put(ctx, mediaKey(id), bytes.NewReader(img.Data)); err != nil {
+			cfg.deleteImages(ctx, ids)
+			return nil, err
+		}
+
+		if err := cfg.blobs.Put(ctx, thumbnailKey(id), bytes.NewReader(img.Thumbnail)); err != nil {
+			cfg.deleteImages(ctx, ids)
+			return nil, err
+		}
+	}
+
+	return ids, nil
+}
+
+// deleteImages removes stored images and thumbnails. Failures only leave
+// unreachable blobs behind, so they are logged rather than returned.
+func (cfg *apiConfig) deleteImages(ctx context.Context, ids []uuid.UUID) {
+	for _, id := range ids {
+		for _, key := range []string{mediaKey(id), thumbnailKey(id)} {
+			if err := cfg.blobs.Delete(ctx, key); err != nil {
+				logger(ctx).Error("Error deleting media blob", "key", key, "error", err)
+			}
+		}
+	}
+}
+
+// saveChirpAttachments records the images stored under ids as attachments of
+// chirpID, in upload order. It is run with transactional queries alongside
+// the write that created the Chirp.
+func saveChirpAttachments(
+	ctx context.Context,
+	qtx *database.Queries,
+	chirpID uuid.UUID,
+	ids []uuid.UUID,
+	images []media.Image,
+) error {
+	for i, img := range images {
+		params := database.CreateChirpAttachmentParams{
+			ID:          ids[i],
+			ChirpID:     chirpID,
+			Position:    int32(i),
+			ContentType: img.ContentType,
+			Width:       int32(img.Width),
+			Height:      int32(img.Height),
+		}
+		if err := qtx.CreateChirpAttachment(ctx, params); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadChirpMedia sets Media on each of chirps using a single query.
+func (cfg *apiConfig) loadChirpMedia(ctx context.Context, chirps []Chirp) error {
+	for i := range chirps {
+		chirps[i].Media = []Media{}
+	}
+
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	attachments, err := cfg.db.GetChirpAttachments(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+
+	attached := make(map[uuid.UUID][]Media, len(chirps))
+	for _, attachment := range attachments {
+		attached[attachment.ChirpID] = append(attached[attachment.ChirpID], Media{
+			ID:           attachment.ID,
+			ContentType:  attachment.ContentType,
+			Width:        attachment.Width,
+			Height:       attachment.Height,
+			URL:          "/api/media/" + attachment.ID.String(),
+			ThumbnailURL: "/api/media/" + attachment.ID.String() + "/thumbnail",
+		})
+	}
+
+	for i := range chirps {
+		if m, ok := attached[chirps[i].ID]; ok {
+			chirps[i].Media = m
+		}
+	}
+
+	return nil
+}
+
+// parseOptionalUUID parses a form value that may be left empty.
+func parseOptionalUUID(value string) (*uuid.UUID, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+func mediaKey(id uuid.UUID) string {
+	return "media/" + id.String()
+}
+
+func thumbnailKey(id uuid.UUID) string {
+	return "media/" + id.String() + "-thumbnail"
+}
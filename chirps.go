@@ -5,85 +5,163 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
-	"log"
 	"net/http"
-	"slices"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/keithcrooks/chirpy/internal/auth"
 	"github.com/keithcrooks/chirpy/internal/database"
+	"github.com/keithcrooks/chirpy/internal/media"
+	"github.com/keithcrooks/chirpy/internal/moderation"
 )
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          uuid.UUID    `json:"id"`
+	Body        string       `json:"body"`
+	UserID      uuid.UUID    `json:"user_id"`
+	ReplyToID   *uuid.UUID   `json:"reply_to_id,omitempty"`
+	RechirpOfID *uuid.UUID   `json:"rechirp_of_id,omitempty"`
+	RechirpOf   *QuotedChirp `json:"rechirp_of,omitempty"`
+	QuoteOfID   *uuid.UUID   `json:"quote_of_id,omitempty"`
+	QuoteOf     *QuotedChirp `json:"quote_of,omitempty"`
+	Mentions    []Mention    `json:"mentions"`
+	Media       []Media      `json:"media"`
+	LikeCount   int32        `json:"like_count"`
+	LikedByMe   bool         `json:"liked_by_me"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
 }
 
 type Chirps struct {
-	Entries []Chirp
+	Entries    []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
 }
 
+const maxChirpLength = 140
+
 type ValidResponse struct {
 	Valid bool `json:"valid"`
 }
 
 func (cfg *apiConfig) handlerCreateChirp(w http.ResponseWriter, req *http.Request) {
-	chirp, err := validateChirp(req)
+	// Authenticate before reading the body, so that only users who may post
+	// can make the server decode uploaded images.
+	token, err := auth.GetBearerToken(req.Header)
 	if err != nil {
-		log.Printf("Error validating Chirp: %v", err)
-		respondWithError(w, http.StatusBadRequest, err.Error())
+		logger(req.Context()).Warn("Error getting Bearer token", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid bearer token")
 		return
 	}
 
-	token, err := auth.GetBearerToken(req.Header)
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
 	if err != nil {
-		log.Printf("Error getting Bearer token: %v", err)
-		respondWithError(w, http.StatusBadRequest, "Invalid bearer token")
+		logger(req.Context()).Warn("Error validating JWT", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+	setRequestUser(req.Context(), userID)
+
+	if !cfg.requireActiveUser(w, req, userID) {
 		return
 	}
 
-	chirp.UserID, err = auth.ValidateJWT(token, cfg.tokenSecret)
+	var chirp Chirp
+	var images []media.Image
+	if isMultipartRequest(req) {
+		chirp, images, err = parseChirpUpload(w, req)
+	} else {
+		chirp, err = validateChirp(req)
+	}
 	if err != nil {
-		log.Printf("Error validating JWT: %v", err)
-		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		logger(req.Context()).Warn("Error validating Chirp", "error", err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	chirp.UserID = userID
+
+	replyToID := uuid.NullUUID{}
+	if chirp.ReplyToID != nil {
+		parent, err := cfg.db.GetChirp(req.Context(), *chirp.ReplyToID)
+		if err != nil {
+			logger(req.Context()).Error("Error getting parent Chirp", "error", err)
+
+			switch err {
+			case sql.ErrNoRows:
+				respondWithError(w, http.StatusBadRequest, "Chirp being replied to does not exist")
+			default:
+				respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirp")
+			}
+			return
+		}
+
+		if parent.DeletedAt.Valid {
+			respondWithError(w, http.StatusBadRequest, "Chirp being replied to does not exist")
+			return
+		}
+		replyToID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	quoteOfID := uuid.NullUUID{}
+	if chirp.QuoteOfID != nil {
+		quoted, err := cfg.getRechirpableChirp(req.Context(), *chirp.QuoteOfID)
+		if err != nil {
+			logger(req.Context()).Error("Error getting quoted Chirp", "error", err)
+			respondWithError(w, http.StatusBadRequest, "Chirp being quoted does not exist")
+			return
+		}
+		quoteOfID = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+	}
 
-	filterChirp(&chirp)
+	moderated, ok := cfg.moderateChirpBody(w, req, chirp.Body)
+	if !ok {
+		return
+	}
 
-	params := database.CreateChirpParams{Body: chirp.Body, UserID: chirp.UserID}
-	dbChirp, err := cfg.db.CreateChirp(req.Context(), params)
+	params := database.CreateChirpParams{
+		Body:      moderated.Body,
+		UserID:    chirp.UserID,
+		ReplyToID: replyToID,
+		QuoteOfID: quoteOfID,
+	}
+	dbChirp, err := cfg.createChirp(req.Context(), params, images, moderated.Matches)
 	if err != nil {
+		// The Chirp being replied to or quoted was deleted since it was
+		// looked up above.
+		if isForeignKeyViolation(err, replyToKey) {
+			respondWithError(w, http.StatusBadRequest, "Chirp being replied to does not exist")
+			return
+		}
+		if isForeignKeyViolation(err, quoteOfKey) {
+			respondWithError(w, http.StatusBadRequest, "Chirp being quoted does not exist")
+			return
+		}
+
 		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	chirp.ID = dbChirp.ID
-	chirp.CreatedAt = dbChirp.CreatedAt
-	chirp.UpdatedAt = dbChirp.UpdatedAt
+	chirps := []Chirp{chirpFromDB(dbChirp)}
+	if err := cfg.decorateChirps(req.Context(), uuid.NullUUID{}, chirps); err != nil {
+		logger(req.Context()).Error("Error decorating Chirp", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirp")
+		return
+	}
 
-	respondWithJSON(w, http.StatusCreated, chirp)
+	respondWithJSON(w, http.StatusCreated, chirps[0])
 }
 
 func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Request) {
 	chirpID := req.PathValue("chirpID")
 	chirpUUID, err := uuid.Parse(chirpID)
 	if err != nil {
-		log.Printf("Error parsing Chirp ID: %s", err)
+		logger(req.Context()).Warn("Error parsing Chirp ID", "error", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID")
 		return
 	}
 
-	log.Printf("chirpUUID: %v", chirpUUID)
-
 	dbChirp, err := cfg.db.GetChirp(req.Context(), chirpUUID)
 	if err != nil {
-		log.Printf("Error getting Chirp from DB: %s", err)
+		logger(req.Context()).Error("Error getting Chirp from DB", "error", err)
 
 		switch err {
 		case sql.ErrNoRows:
@@ -94,20 +172,33 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
+	if dbChirp.DeletedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
 	token, err := auth.GetBearerToken(req.Header)
 	if err != nil {
-		log.Printf("Error getting getting bearer token: %v", err)
+		logger(req.Context()).Warn("Error getting bearer token", "error", err)
 		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
 		return
 	}
 
 	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err == nil {
+		setRequestUser(req.Context(), userID)
+	}
 	if err != nil || userID != dbChirp.UserID {
 		respondWithError(w, http.StatusForbidden, http.StatusText(http.StatusUnauthorized))
 		return
 	}
 
-	if err := cfg.db.DeleteChirp(req.Context(), chirpUUID); err != nil {
+	if !cfg.requireActiveUser(w, req, userID) {
+		return
+	}
+
+	if err := cfg.deleteChirp(req.Context(), chirpUUID); err != nil {
+		logger(req.Context()).Error("Error deleting Chirp", "error", err)
 		respondWithError(w, http.StatusInternalServerError, "Error deleting Chirp")
 		return
 	}
@@ -116,48 +207,72 @@ func (cfg *apiConfig) handlerDeleteChirp(w http.ResponseWriter, req *http.Reques
 }
 
 func (cfg *apiConfig) handlerGetAllChirps(w http.ResponseWriter, req *http.Request) {
-	authorID := req.URL.Query().Get("author_id")
+	viewerID, err := cfg.getOptionalUserIDFromRequest(req)
+	if err != nil {
+		logger(req.Context()).Warn("Error authenticating user", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	query := req.URL.Query()
 
-	dbChirps, err := cfg.getChirps(req.Context(), authorID)
+	p, err := parseSortablePage(query)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authorID, err := parseAuthorID(query)
+	if err != nil {
+		logger(req.Context()).Warn("Error parsing author ID", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+
+	// Fetch one extra row so we know whether another page follows.
+	dbChirps, err := cfg.getChirps(req.Context(), authorID, p.Cursor, p.Limit+1, p.Desc)
+	if err != nil {
+		logger(req.Context()).Error("Error getting Chirps from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirps")
 		return
 	}
 
 	chirps := Chirps{Entries: []Chirp{}}
 
-	for _, dbChirp := range dbChirps {
-		chirp := Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID,
-		}
+	dbChirps, chirps.NextCursor = trimPage(dbChirps, p.Limit, chirpCursor)
 
-		chirps.Entries = append(chirps.Entries, chirp)
+	for _, dbChirp := range dbChirps {
+		chirps.Entries = append(chirps.Entries, chirpFromDB(dbChirp))
 	}
 
-	sort := req.URL.Query().Get("sort")
-	chirps.Entries = sortChirps(chirps.Entries, sort)
+	if err := cfg.decorateChirps(req.Context(), viewerID, chirps.Entries); err != nil {
+		logger(req.Context()).Error("Error decorating Chirps", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirps")
+		return
+	}
 
-	respondWithJSON(w, http.StatusOK, chirps.Entries)
+	respondWithJSON(w, http.StatusOK, chirps)
 }
 
 func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, req *http.Request) {
 	chirpID := req.PathValue("chirpID")
 	chirpUUID, err := uuid.Parse(chirpID)
 	if err != nil {
-		log.Printf("Error parsing Chirp ID: %s", err)
+		logger(req.Context()).Warn("Error parsing Chirp ID", "error", err)
 		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID")
 		return
 	}
 
-	log.Printf("chirpUUID: %v", chirpUUID)
+	viewerID, err := cfg.getOptionalUserIDFromRequest(req)
+	if err != nil {
+		logger(req.Context()).Warn("Error authenticating user", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
 
 	dbChirp, err := cfg.db.GetChirp(req.Context(), chirpUUID)
 	if err != nil {
-		log.Printf("Error getting Chirp from DB: %s", err)
+		logger(req.Context()).Error("Error getting Chirp from DB", "error", err)
 
 		switch err {
 		case sql.ErrNoRows:
@@ -168,79 +283,394 @@ func (cfg *apiConfig) handlerGetChirp(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	chirp := Chirp{
-		ID:        dbChirp.ID,
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
+	if dbChirp.DeletedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
 	}
 
-	respondWithJSON(w, http.StatusOK, chirp)
+	chirps := []Chirp{chirpFromDB(dbChirp)}
+	if err := cfg.decorateChirps(req.Context(), viewerID, chirps); err != nil {
+		logger(req.Context()).Error("Error decorating Chirps", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirp")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps[0])
 }
 
-func (cfg *apiConfig) getChirps(ctx context.Context, authorID string) ([]database.Chirp, error) {
-	if authorID == "" {
-		return cfg.db.GetAllChirps(ctx)
+func (cfg *apiConfig) handlerUpdateChirp(w http.ResponseWriter, req *http.Request) {
+	chirpID := req.PathValue("chirpID")
+	chirpUUID, err := uuid.Parse(chirpID)
+	if err != nil {
+		logger(req.Context()).Warn("Error parsing Chirp ID", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID")
+		return
 	}
 
-	authorUUID, err := uuid.Parse(authorID)
+	token, err := auth.GetBearerToken(req.Header)
 	if err != nil {
-		log.Printf("Error parsing author ID: %v", err)
-		return nil, errors.New("invalid author ID")
+		logger(req.Context()).Warn("Error getting bearer token", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
 	}
 
-	return cfg.db.GetAllChirpsByAuthor(ctx, authorUUID)
-}
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		logger(req.Context()).Warn("Error validating JWT", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+	setRequestUser(req.Context(), userID)
 
-func filterChirp(chirp *Chirp) {
-	const (
-		replacement = "****"
-		sep         = " "
-	)
+	if !cfg.requireActiveUser(w, req, userID) {
+		return
+	}
+
+	chirp, err := validateChirp(req)
+	if err != nil {
+		logger(req.Context()).Warn("Error validating Chirp", "error", err)
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	words := strings.Split(chirp.Body, sep)
+	dbChirp, err := cfg.db.GetChirp(req.Context(), chirpUUID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting Chirp from DB", "error", err)
 
-	for i, word := range words {
-		if isProfane(word) {
-			words[i] = replacement
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusNotFound, "Chirp not found")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirp")
 		}
+		return
+	}
+
+	if dbChirp.DeletedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	if userID != dbChirp.UserID {
+		respondWithError(w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		return
+	}
+
+	if dbChirp.RechirpOfID.Valid {
+		respondWithError(w, http.StatusBadRequest, "Rechirps cannot be edited")
+		return
+	}
+
+	if time.Since(dbChirp.CreatedAt) > cfg.chirpEditWindow {
+		respondWithError(w, http.StatusForbidden, "Edit window has expired")
+		return
+	}
+
+	moderated, ok := cfg.moderateChirpBody(w, req, chirp.Body)
+	if !ok {
+		return
+	}
+
+	dbChirp, err = cfg.updateChirpBody(req.Context(), dbChirp, moderated.Body, moderated.Matches)
+	if err != nil {
+		logger(req.Context()).Error("Error updating Chirp", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Error updating Chirp")
+		return
 	}
 
-	chirp.Body = strings.Join(words, sep)
+	chirps := []Chirp{chirpFromDB(dbChirp)}
+	if err := cfg.decorateChirps(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirps); err != nil {
+		logger(req.Context()).Error("Error decorating Chirp", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirp")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps[0])
 }
 
-func isProfane(word string) bool {
-	profaneWords := []string{"kerfuffle", "sharbert", "fornax"}
+func (cfg *apiConfig) getChirps(
+	ctx context.Context,
+	authorID uuid.NullUUID,
+	after *cursor,
+	limit int32,
+	desc bool,
+) ([]database.Chirp, error) {
+	cursorCreatedAt, cursorID := cursorParams(after)
+
+	if desc {
+		return cfg.db.ListChirpsDesc(ctx, database.ListChirpsDescParams{
+			AuthorID:        authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID:        cursorID,
+			Limit:           limit,
+		})
+	}
+
+	return cfg.db.ListChirpsAsc(ctx, database.ListChirpsAscParams{
+		AuthorID:        authorID,
+		CursorCreatedAt: cursorCreatedAt,
+		CursorID:        cursorID,
+		Limit:           limit,
+	})
+}
+
+// createChirp stores a new Chirp along with its images, the hashtags and
+// mentions found in its body and any moderation flags in a single
+// transaction. The images are written
+// to the blob store first and removed again if the transaction fails.
+func (cfg *apiConfig) createChirp(
+	ctx context.Context,
+	params database.CreateChirpParams,
+	images []media.Image,
+	matches []moderation.Match,
+) (database.Chirp, error) {
+	attachmentIDs, err := cfg.storeImages(ctx, images)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			cfg.deleteImages(context.WithoutCancel(ctx), attachmentIDs)
+		}
+	}()
+
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.withTx(tx)
+
+	dbChirp, err := qtx.CreateChirp(ctx, params)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := saveChirpHashtags(ctx, qtx, dbChirp); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := saveChirpMentions(ctx, qtx, dbChirp); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := saveChirpAttachments(ctx, qtx, dbChirp.ID, attachmentIDs, images); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := saveChirpFlags(ctx, qtx, dbChirp, matches); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if dbChirp.ReplyToID.Valid {
+		parent, err := qtx.GetChirp(ctx, dbChirp.ReplyToID.UUID)
+		if err != nil {
+			return database.Chirp{}, err
+		}
 
-	for _, profaneWord := range profaneWords {
-		if strings.ToLower(word) == profaneWord {
-			return true
+		chirpID := uuid.NullUUID{UUID: dbChirp.ID, Valid: true}
+		if err := notify(ctx, qtx, parent.UserID, dbChirp.UserID, notificationReply, chirpID); err != nil {
+			return database.Chirp{}, err
 		}
 	}
 
-	return false
+	if err := tx.Commit(); err != nil {
+		return database.Chirp{}, err
+	}
+	committed = true
+
+	return dbChirp, nil
 }
 
-func sortChirps(chirps []Chirp, sort string) []Chirp {
-	if strings.ToLower(sort) == "desc" {
-		slices.SortFunc(chirps, func(a, b Chirp) int {
-			return b.CreatedAt.Compare(a.CreatedAt)
-		})
+// Foreign keys from a Chirp to the Chirps it replies to and quotes.
+const (
+	replyToKey = "chirps_reply_to_id_fkey"
+	quoteOfKey = "chirps_quote_of_id_fkey"
+)
+
+// deleteChirp removes a Chirp and its images. Chirps that have replies or
+// quotes are replaced with a tombstone instead so the rest of the conversation
+// stays reachable. The Chirp is locked first, so a reply or quote posted
+// meanwhile waits for the delete and cannot be cut off from it.
+func (cfg *apiConfig) deleteChirp(ctx context.Context, chirpID uuid.UUID) error {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.withTx(tx)
+
+	if err := qtx.LockChirp(ctx, chirpID); err != nil {
+		return err
+	}
+
+	attachments, err := qtx.GetChirpAttachments(ctx, []uuid.UUID{chirpID})
+	if err != nil {
+		return err
+	}
+
+	attachmentIDs := make([]uuid.UUID, 0, len(attachments))
+	for _, attachment := range attachments {
+		attachmentIDs = append(attachmentIDs, attachment.ID)
+	}
+
+	hasDependents, err := qtx.ChirpHasDependents(ctx, chirpID)
+	if err != nil {
+		return err
+	}
+
+	if hasDependents {
+		err = tombstoneChirp(ctx, qtx, chirpID)
+	} else {
+		err = qtx.DeleteChirp(ctx, chirpID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	cfg.deleteImages(ctx, attachmentIDs)
+	return nil
+}
+
+// tombstoneChirp clears a Chirp's body, revisions, hashtags, mentions and
+// attachments but keeps its row, so replies and quotes still point at it.
+func tombstoneChirp(ctx context.Context, qtx *database.Queries, chirpID uuid.UUID) error {
+	if err := qtx.DeleteChirpRevisions(ctx, chirpID); err != nil {
+		return err
+	}
+
+	if err := qtx.DeleteChirpHashtags(ctx, chirpID); err != nil {
+		return err
+	}
+
+	if err := qtx.DeleteChirpMentions(ctx, chirpID); err != nil {
+		return err
+	}
+
+	if err := qtx.DeleteChirpAttachments(ctx, chirpID); err != nil {
+		return err
+	}
+
+	return qtx.TombstoneChirp(ctx, chirpID)
+}
+
+// updateChirpBody stores the current body of dbChirp as a revision and
+// replaces it with body, re-indexing its hashtags and mentions and recording
+// any moderation flags, in a single transaction.
+func (cfg *apiConfig) updateChirpBody(
+	ctx context.Context,
+	dbChirp database.Chirp,
+	body string,
+	matches []moderation.Match,
+) (database.Chirp, error) {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.withTx(tx)
+
+	revision := database.CreateChirpRevisionParams{ChirpID: dbChirp.ID, Body: dbChirp.Body}
+	if _, err := qtx.CreateChirpRevision(ctx, revision); err != nil {
+		return database.Chirp{}, err
+	}
+
+	params := database.UpdateChirpBodyParams{Body: body, ID: dbChirp.ID}
+	updated, err := qtx.UpdateChirpBody(ctx, params)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := qtx.DeleteChirpHashtags(ctx, dbChirp.ID); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := saveChirpHashtags(ctx, qtx, updated); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := qtx.DeleteChirpMentions(ctx, dbChirp.ID); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := saveChirpMentions(ctx, qtx, updated); err != nil {
+		return database.Chirp{}, err
+	}
+
+	if err := saveChirpFlags(ctx, qtx, updated, matches); err != nil {
+		return database.Chirp{}, err
+	}
+
+	return updated, tx.Commit()
+}
+
+// chirpCursor returns the position of dbChirp in a list of Chirps.
+func chirpCursor(dbChirp database.Chirp) cursor {
+	return cursor{CreatedAt: dbChirp.CreatedAt, ID: dbChirp.ID}
+}
+
+func chirpFromDB(dbChirp database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:        dbChirp.ID,
+		CreatedAt: dbChirp.CreatedAt,
+		UpdatedAt: dbChirp.UpdatedAt,
+		Body:      dbChirp.Body,
+		UserID:    dbChirp.UserID,
+		LikeCount: dbChirp.LikeCount,
+	}
+
+	if dbChirp.ReplyToID.Valid {
+		chirp.ReplyToID = &dbChirp.ReplyToID.UUID
+	}
+
+	if dbChirp.RechirpOfID.Valid {
+		chirp.RechirpOfID = &dbChirp.RechirpOfID.UUID
+	}
+
+	if dbChirp.QuoteOfID.Valid {
+		chirp.QuoteOfID = &dbChirp.QuoteOfID.UUID
+	}
+
+	return chirp
+}
+
+// decorateChirps fills in the fields of chirps that are not stored on the
+// Chirp row itself, batching lookups so a page costs a fixed number of
+// queries. viewerID may be invalid for anonymous requests.
+func (cfg *apiConfig) decorateChirps(ctx context.Context, viewerID uuid.NullUUID, chirps []Chirp) error {
+	if err := cfg.markLikedChirps(ctx, viewerID, chirps); err != nil {
+		return err
+	}
+
+	if err := cfg.loadChirpMentions(ctx, chirps); err != nil {
+		return err
+	}
+
+	if err := cfg.loadChirpMedia(ctx, chirps); err != nil {
+		return err
 	}
 
-	return chirps
+	return cfg.embedReferencedChirps(ctx, chirps)
 }
 
 func validateChirp(req *http.Request) (Chirp, error) {
 	decoder := json.NewDecoder(req.Body)
 	chirp := Chirp{}
 	if err := decoder.Decode(&chirp); err != nil {
-		log.Printf("Error decoding Chirp: %s", err)
+		logger(req.Context()).Warn("Error decoding Chirp", "error", err)
 		return chirp, errors.New("Could not read Chirp")
 	}
 
-	if len(chirp.Body) > 140 {
+	if len(chirp.Body) > maxChirpLength {
 		return chirp, errors.New("Chirp is too long")
 	}
 
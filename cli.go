@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/keithcrooks/chirpy/internal/auth"
+	"github.com/keithcrooks/chirpy/internal/config"
+	"github.com/keithcrooks/chirpy/internal/database"
+)
+
+const usage = `usage: chirpy [flags] [command]
+
+With no command, chirpy starts the server.
+
+commands:
+  serve                 start the server
+  config                print the effective config, with secrets redacted
+  migrate up            apply every pending migration
+  migrate down          roll back the latest migration
+  migrate redo          roll back the latest migration and apply it again
+  migrate status        list the migrations and when they were applied
+  seed                  load the fixtures file into the database
+  create-admin <email>  create an admin, reading their password from stdin
+  make-admin <email>    give the user with this email address the admin role
+
+flags:`
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, usage)
+	config.PrintFlags(w)
+}
+
+// runCommand runs a command-line subcommand instead of the server.
+func runCommand(ctx context.Context, cfg *config.Config, args []string) error {
+	switch args[0] {
+	case "config":
+		return cfg.Print(os.Stdout)
+	case "migrate":
+		if len(args) != 2 || !slices.Contains([]string{"up", "down", "redo", "status"}, args[1]) {
+			return errors.New("usage: chirpy migrate up|down|redo|status")
+		}
+
+		db, err := openDB(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		return migrate(ctx, db, args[1], os.Stdout)
+	case "seed":
+		if len(args) != 1 {
+			return errors.New("usage: chirpy seed")
+		}
+
+		db, err := openDB(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		return seed(ctx, db, cfg.FixturesFile)
+	case "create-admin":
+		if len(args) != 2 {
+			return errors.New("usage: chirpy create-admin <email>")
+		}
+
+		password, err := readPassword(os.Stdin, os.Stderr)
+		if err != nil {
+			return err
+		}
+
+		db, err := openDB(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		return createAdmin(ctx, db, args[1], password)
+	case "make-admin":
+		if len(args) != 2 {
+			return errors.New("usage: chirpy make-admin <email>")
+		}
+
+		db, err := openDB(cfg)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		return makeAdmin(ctx, database.New(db), args[1])
+	case "help":
+		printUsage(os.Stdout)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q; run chirpy help for usage", args[0])
+	}
+}
+
+// openDB opens the database for a command, which unlike the server needs no
+// other settings.
+func openDB(cfg *config.Config) (*sql.DB, error) {
+	if cfg.DBURL == "" {
+		return nil, errors.New("DB_URL must be set")
+	}
+	return sql.Open("postgres", cfg.DBURL)
+}
+
+// seed loads the fixtures in path, without emptying the database first as a
+// development reset does.
+func seed(ctx context.Context, db *sql.DB, path string) error {
+	f, err := loadFixtures(path)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	users, chirps, err := seedFixtures(ctx, database.New(tx), f)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Seeded %d users and %d Chirps\n", users, chirps)
+	return nil
+}
+
+// readPassword reads a password from the first line of r, prompting on
+// prompt. The password is echoed if r is a terminal, so scripts should pipe
+// it in.
+func readPassword(r io.Reader, prompt io.Writer) (string, error) {
+	fmt.Fprint(prompt, "Password: ")
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password must not be empty")
+	}
+	return password, nil
+}
+
+// createAdmin creates a user with the admin role, for deployments that have
+// no users yet.
+func createAdmin(ctx context.Context, db *sql.DB, email, password string) error {
+	hashedPassword, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := database.New(tx)
+
+	dbUser, err := qtx.CreateUser(ctx, database.CreateUserParams{Email: email, HashedPassword: hashedPassword})
+	if err != nil {
+		if isUniqueViolation(err, emailIndex) {
+			return fmt.Errorf("a user with email %s already exists; use make-admin", email)
+		}
+		return err
+	}
+
+	params := database.UpdateUserRoleParams{Role: roleAdmin, ID: dbUser.ID}
+	if _, err := qtx.UpdateUserRole(ctx, params); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created admin %s\n", email)
+	return nil
+}
+
+// makeAdmin promotes an existing user to admin, to bootstrap the first admin
+// of a new deployment. Later admins can be appointed through the API.
+func makeAdmin(ctx context.Context, db *database.Queries, email string) error {
+	dbUser, err := db.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("no user with email %s", email)
+		}
+		return err
+	}
+
+	params := database.UpdateUserRoleParams{Role: roleAdmin, ID: dbUser.ID}
+	if _, err := db.UpdateUserRole(ctx, params); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is now an admin\n", email)
+	return nil
+}
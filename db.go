@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/keithcrooks/chirpy/internal/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentDB wraps db so that the queries run through it are timed and
+// traced.
+func (m *serverMetrics) instrumentDB(db database.DBTX) database.DBTX {
+	return &instrumentedDB{db: db, duration: m.queryDuration}
+}
+
+// instrumentedDB times queries by the name sqlc gives them and records each
+// one as a span, a child of the request span when there is one. Queries are
+// timed until they return, which for QueryContext is before the rows are
+// read.
+type instrumentedDB struct {
+	db       database.DBTX
+	duration *prometheus.HistogramVec
+}
+
+func (i *instrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := i.start(ctx, query)
+	result, err := i.db.ExecContext(ctx, query, args...)
+	done(err)
+	return result, err
+}
+
+func (i *instrumentedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return i.db.PrepareContext(ctx, query)
+}
+
+func (i *instrumentedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, done := i.start(ctx, query)
+	rows, err := i.db.QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+func (i *instrumentedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := i.start(ctx, query)
+	row := i.db.QueryRowContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+// start begins timing query. The returned function ends the span, recording
+// err unless it only means that no rows were found.
+func (i *instrumentedDB) start(ctx context.Context, query string) (context.Context, func(error)) {
+	name := queryName(query)
+	start := time.Now()
+
+	ctx, span := tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemNamePostgreSQL,
+			semconv.DBOperationName(name),
+			semconv.DBQueryText(query),
+		),
+	)
+
+	return ctx, func(err error) {
+		i.duration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// queryName extracts the name from the "-- name: GetChirp :one" comment that
+// starts every query generated by sqlc. Other queries are named "other".
+func queryName(query string) string {
+	rest, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return "other"
+	}
+
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/auth"
+	"github.com/keithcrooks/chirpy/internal/database"
+)
+
+// fixtures is sample data that a reset can load into a development database.
+// Chirps name their author by email address.
+type fixtures struct {
+	Users  []fixtureUser  `json:"users"`
+	Chirps []fixtureChirp `json:"chirps"`
+}
+
+type fixtureUser struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+type fixtureChirp struct {
+	Author string `json:"author"`
+	Body   string `json:"body"`
+}
+
+func loadFixtures(path string) (fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fixtures{}, err
+	}
+
+	var f fixtures
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fixtures{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+// seedFixtures inserts f using transactional queries, returning how many
+// users and Chirps it created.
+func seedFixtures(ctx context.Context, qtx *database.Queries, f fixtures) (int, int, error) {
+	userIDs := map[string]uuid.UUID{}
+
+	for _, user := range f.Users {
+		hashedPassword, err := auth.HashPassword(user.Password)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		params := database.CreateUserParams{
+			Email:          user.Email,
+			HashedPassword: hashedPassword,
+			Username:       sql.NullString{String: user.Username, Valid: user.Username != ""},
+		}
+		dbUser, err := qtx.CreateUser(ctx, params)
+		if err != nil {
+			return 0, 0, fmt.Errorf("creating user %s: %w", user.Email, err)
+		}
+
+		if user.Role != "" && user.Role != dbUser.Role {
+			if _, ok := roleRanks[user.Role]; !ok {
+				return 0, 0, fmt.Errorf("user %s has unknown role %q", user.Email, user.Role)
+			}
+
+			params := database.UpdateUserRoleParams{Role: user.Role, ID: dbUser.ID}
+			if _, err := qtx.UpdateUserRole(ctx, params); err != nil {
+				return 0, 0, err
+			}
+		}
+
+		userIDs[user.Email] = dbUser.ID
+	}
+
+	for _, chirp := range f.Chirps {
+		if len(chirp.Body) > maxChirpLength {
+			return 0, 0, fmt.Errorf("chirp by %s is too long", chirp.Author)
+		}
+
+		authorID, ok := userIDs[chirp.Author]
+		if !ok {
+			// The author may be a user that was kept by this reset.
+			author, err := qtx.GetUserByEmail(ctx, chirp.Author)
+			if err != nil {
+				return 0, 0, fmt.Errorf("finding chirp author %s: %w", chirp.Author, err)
+			}
+			authorID = author.ID
+		}
+
+		dbChirp, err := qtx.CreateChirp(ctx, database.CreateChirpParams{Body: chirp.Body, UserID: authorID})
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if err := saveChirpHashtags(ctx, qtx, dbChirp); err != nil {
+			return 0, 0, err
+		}
+
+		if err := saveChirpMentions(ctx, qtx, dbChirp); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return len(f.Users), len(f.Chirps), nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/database"
+	"github.com/keithcrooks/chirpy/internal/entities"
+)
+
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type Follows struct {
+	Entries    []Follow `json:"users"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) handlerFollowUser(w http.ResponseWriter, req *http.Request) {
+	followerID, followeeID, ok := cfg.getFollowRequest(w, req)
+	if !ok {
+		return
+	}
+
+	if followerID == followeeID {
+		respondWithError(w, http.StatusBadRequest, "Users cannot follow themselves")
+		return
+	}
+
+	if err := cfg.createFollow(req.Context(), followerID, followeeID); err != nil {
+		logger(req.Context()).Error("Error creating follow", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Error following user")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) handlerUnfollowUser(w http.ResponseWriter, req *http.Request) {
+	followerID, followeeID, ok := cfg.getFollowRequest(w, req)
+	if !ok {
+		return
+	}
+
+	params := database.DeleteFollowParams{FollowerID: followerID, FolloweeID: followeeID}
+	if err := cfg.db.DeleteFollow(req.Context(), params); err != nil {
+		logger(req.Context()).Error("Error deleting follow", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Error unfollowing user")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func (cfg *apiConfig) handlerGetFollowers(w http.ResponseWriter, req *http.Request) {
+	cfg.respondWithFollows(w, req, cfg.listFollowers)
+}
+
+func (cfg *apiConfig) handlerGetFollowing(w http.ResponseWriter, req *http.Request) {
+	cfg.respondWithFollows(w, req, cfg.listFollowing)
+}
+
+// getFollowRequest authenticates the caller and looks up the user named in
+// the path. It writes an error response and returns false on failure.
+func (cfg *apiConfig) getFollowRequest(w http.ResponseWriter, req *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	followerID, ok := cfg.getActiveUserIDFromRequest(w, req)
+	if !ok {
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	followee, ok := cfg.getUserFromPath(w, req)
+	if !ok {
+		return uuid.UUID{}, uuid.UUID{}, false
+	}
+
+	return followerID, followee.ID, true
+}
+
+// getUserFromPath looks up the user named by the {userID} path value, which
+// may be either a user ID or a username. It writes an error response and
+// returns false on failure.
+func (cfg *apiConfig) getUserFromPath(w http.ResponseWriter, req *http.Request) (database.User, bool) {
+	idOrUsername := req.PathValue("userID")
+
+	var dbUser database.User
+	var err error
+	if userUUID, parseErr := uuid.Parse(idOrUsername); parseErr == nil {
+		dbUser, err = cfg.db.GetUser(req.Context(), userUUID)
+	} else if entities.IsUsername(idOrUsername) {
+		dbUser, err = cfg.db.GetUserByUsername(req.Context(), idOrUsername)
+	} else {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID or username")
+		return database.User{}, false
+	}
+
+	if err != nil {
+		logger(req.Context()).Error("Error getting user from DB", "error", err)
+
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusNotFound, "User not found")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Unknown error getting user")
+		}
+		return database.User{}, false
+	}
+
+	return dbUser, true
+}
+
+// createFollow records a follow and notifies the followed user in one
+// transaction. Following someone twice is a no-op.
+func (cfg *apiConfig) createFollow(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.withTx(tx)
+
+	params := database.CreateFollowParams{FollowerID: followerID, FolloweeID: followeeID}
+	if err := qtx.CreateFollow(ctx, params); err != nil {
+		return err
+	}
+
+	if err := notify(ctx, qtx, followeeID, followerID, notificationFollow, uuid.NullUUID{}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type listFollowsFunc func(ctx context.Context, userID uuid.UUID, after *cursor, limit int32) ([]Follow, error)
+
+func (cfg *apiConfig) respondWithFollows(w http.ResponseWriter, req *http.Request, list listFollowsFunc) {
+	dbUser, ok := cfg.getUserFromPath(w, req)
+	if !ok {
+		return
+	}
+
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := list(req.Context(), dbUser.ID, p.Cursor, p.Limit+1)
+	if err != nil {
+		logger(req.Context()).Error("Error listing follows", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting users")
+		return
+	}
+
+	follows := Follows{Entries: entries}
+
+	follows.Entries, follows.NextCursor = trimPage(entries, p.Limit, func(f Follow) cursor {
+		return cursor{CreatedAt: f.FollowedAt, ID: f.UserID}
+	})
+
+	respondWithJSON(w, http.StatusOK, follows)
+}
+
+func (cfg *apiConfig) listFollowers(ctx context.Context, userID uuid.UUID, after *cursor, limit int32) ([]Follow, error) {
+	params := database.ListFollowersParams{UserID: userID, Limit: limit}
+	params.CursorCreatedAt, params.CursorID = cursorParams(after)
+
+	rows, err := cfg.db.ListFollowers(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	follows := []Follow{}
+	for _, row := range rows {
+		follows = append(follows, Follow{UserID: row.UserID, FollowedAt: row.CreatedAt})
+	}
+
+	return follows, nil
+}
+
+func (cfg *apiConfig) listFollowing(ctx context.Context, userID uuid.UUID, after *cursor, limit int32) ([]Follow, error) {
+	params := database.ListFollowingParams{UserID: userID, Limit: limit}
+	params.CursorCreatedAt, params.CursorID = cursorParams(after)
+
+	rows, err := cfg.db.ListFollowing(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	follows := []Follow{}
+	for _, row := range rows {
+		follows = append(follows, Follow{UserID: row.UserID, FollowedAt: row.CreatedAt})
+	}
+
+	return follows, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/keithcrooks/chirpy/internal/database"
+	"github.com/keithcrooks/chirpy/internal/entities"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+)
+
+type TrendingHashtag struct {
+	Tag        string `json:"tag"`
+	ChirpCount int64  `json:"chirp_count"`
+}
+
+func (cfg *apiConfig) handlerGetHashtagChirps(w http.ResponseWriter, req *http.Request) {
+	tag, ok := entities.NormalizeHashtag(req.PathValue("tag"))
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "Invalid hashtag")
+		return
+	}
+
+	viewerID, err := cfg.getOptionalUserIDFromRequest(req)
+	if err != nil {
+		logger(req.Context()).Warn("Error authenticating user", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	params := database.ListHashtagChirpsParams{Tag: tag, Limit: p.Limit + 1}
+	params.CursorCreatedAt, params.CursorID = cursorParams(p.Cursor)
+
+	dbChirps, err := cfg.db.ListHashtagChirps(req.Context(), params)
+	if err != nil {
+		logger(req.Context()).Error("Error getting hashtag Chirps from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirps")
+		return
+	}
+
+	chirps := Chirps{Entries: []Chirp{}}
+
+	dbChirps, chirps.NextCursor = trimPage(dbChirps, p.Limit, chirpCursor)
+
+	for _, dbChirp := range dbChirps {
+		chirps.Entries = append(chirps.Entries, chirpFromDB(dbChirp))
+	}
+
+	if err := cfg.decorateChirps(req.Context(), viewerID, chirps.Entries); err != nil {
+		logger(req.Context()).Error("Error decorating Chirps", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirps")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
+
+// handlerGetTrendingHashtags returns the hashtags used by the most Chirps in
+// the sliding window given by ?window= (a Go duration such as "6h").
+func (cfg *apiConfig) handlerGetTrendingHashtags(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	window := defaultTrendingWindow
+	if value := query.Get("window"); value != "" {
+		var err error
+		window, err = time.ParseDuration(value)
+		if err != nil || window <= 0 || window > maxTrendingWindow {
+			respondWithError(w, http.StatusBadRequest, "Invalid window")
+			return
+		}
+	}
+
+	if err := checkFixedOrder(query); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, err := parseLimit(query)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	params := database.ListTrendingHashtagsParams{WindowSeconds: window.Seconds(), Limit: limit}
+	rows, err := cfg.db.ListTrendingHashtags(req.Context(), params)
+	if err != nil {
+		logger(req.Context()).Error("Error getting trending hashtags from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting trending hashtags")
+		return
+	}
+
+	trending := []TrendingHashtag{}
+	for _, row := range rows {
+		trending = append(trending, TrendingHashtag{Tag: row.Tag, ChirpCount: row.ChirpCount})
+	}
+
+	respondWithJSON(w, http.StatusOK, trending)
+}
+
+// saveChirpHashtags indexes the hashtags in dbChirp's body. It is run with
+// transactional queries alongside the write that produced the body. Tags are
+// dated by when the Chirp was posted, so that editing an old Chirp does not
+// count its tags towards what is trending now.
+func saveChirpHashtags(ctx context.Context, qtx *database.Queries, dbChirp database.Chirp) error {
+	for _, tag := range entities.Hashtags(dbChirp.Body) {
+		hashtag, err := qtx.UpsertHashtag(ctx, tag)
+		if err != nil {
+			return err
+		}
+
+		params := database.CreateChirpHashtagParams{
+			ChirpID:   dbChirp.ID,
+			HashtagID: hashtag.ID,
+			CreatedAt: dbChirp.CreatedAt,
+		}
+		if err := qtx.CreateChirpHashtag(ctx, params); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -49,13 +49,29 @@ func GetBearerToken(headers http.Header) (string, error) {
 	return token, nil
 }
 
+// tokenClaims are the claims carried by an access token. Role is empty for
+// tokens issued without one.
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role,omitempty"`
+}
+
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return MakeJWTWithRole(userID, "", tokenSecret, expiresIn)
+}
+
+// MakeJWTWithRole is like MakeJWT but also records the user's role, so it can
+// be authorized without a database lookup.
+func MakeJWTWithRole(userID uuid.UUID, role, tokenSecret string, expiresIn time.Duration) (string, error) {
 	nowUTC := time.Now().UTC()
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(nowUTC),
-		ExpiresAt: jwt.NewNumericDate(nowUTC.Add(expiresIn)),
-		Subject:   userID.String(),
+	claims := tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(nowUTC),
+			ExpiresAt: jwt.NewNumericDate(nowUTC.Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Role: role,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -64,7 +80,34 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
+	userID, _, err := ValidateJWTWithExpiry(tokenString, tokenSecret)
+	return userID, err
+}
+
+// ValidateJWTWithExpiry is like ValidateJWT but also returns when the token
+// expires, for long-lived connections that must stop at that point.
+func ValidateJWTWithExpiry(tokenString, tokenSecret string) (uuid.UUID, time.Time, error) {
+	userID, claims, err := parseJWT(tokenString, tokenSecret)
+	if err != nil {
+		return uuid.UUID{}, time.Time{}, err
+	}
+
+	return userID, claims.ExpiresAt.Time, nil
+}
+
+// ValidateJWTWithRole is like ValidateJWT but also returns the role the token
+// was issued with.
+func ValidateJWTWithRole(tokenString, tokenSecret string) (uuid.UUID, string, error) {
+	userID, claims, err := parseJWT(tokenString, tokenSecret)
+	if err != nil {
+		return uuid.UUID{}, "", err
+	}
+
+	return userID, claims.Role, nil
+}
+
+func parseJWT(tokenString, tokenSecret string) (uuid.UUID, *tokenClaims, error) {
+	claims := &tokenClaims{}
 
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
 		return []byte(tokenSecret), nil
@@ -72,19 +115,32 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc)
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, nil, err
 	}
 
 	if !token.Valid {
-		return uuid.UUID{}, errors.New("token is not valid")
+		return uuid.UUID{}, nil, errors.New("token is not valid")
 	}
 
 	subject, err := claims.GetSubject()
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, nil, err
+	}
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil {
+		return uuid.UUID{}, nil, err
+	}
+	if expiresAt == nil {
+		return uuid.UUID{}, nil, errors.New("token has no expiry")
+	}
+
+	userID, err := uuid.Parse(subject)
+	if err != nil {
+		return uuid.UUID{}, nil, err
 	}
 
-	return uuid.Parse(subject)
+	return userID, claims, nil
 }
 
 func MakeRefreshToken() (string, error) {
@@ -176,3 +176,111 @@ func TestValidateJWT(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateJWTWithExpiry(t *testing.T) {
+	tokenSecret := rand.Text()
+	userID := uuid.New()
+	token, _ := MakeJWT(userID, tokenSecret, time.Hour)
+	expiredToken, _ := MakeJWT(userID, tokenSecret, -time.Minute)
+
+	tests := []struct {
+		name    string
+		token   string
+		secret  string
+		wantErr bool
+	}{
+		{
+			name:    "Valid token",
+			token:   token,
+			secret:  tokenSecret,
+			wantErr: false,
+		},
+		{
+			name:    "Expired token",
+			token:   expiredToken,
+			secret:  tokenSecret,
+			wantErr: true,
+		},
+		{
+			name:    "Wrong secret",
+			token:   token,
+			secret:  rand.Text(),
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, expiresAt, err := ValidateJWTWithExpiry(test.token, test.secret)
+			if (err != nil) != test.wantErr {
+				t.Errorf("ValidateJWTWithExpiry() error = %v, wantErr %v", err, test.wantErr)
+			}
+
+			if test.wantErr {
+				return
+			}
+
+			if result != userID {
+				t.Errorf("ValidateJWTWithExpiry() expects %v, got %v", userID, result)
+			}
+
+			if d := time.Until(expiresAt); d <= 0 || d > time.Hour {
+				t.Errorf("ValidateJWTWithExpiry() expiry %v is not within the next hour", expiresAt)
+			}
+		})
+	}
+}
+
+func TestValidateJWTWithRole(t *testing.T) {
+	tokenSecret := rand.Text()
+	userID := uuid.New()
+	adminToken, _ := MakeJWTWithRole(userID, "admin", tokenSecret, time.Hour)
+	plainToken, _ := MakeJWT(userID, tokenSecret, time.Hour)
+
+	tests := []struct {
+		name     string
+		token    string
+		wantRole string
+		wantErr  bool
+	}{
+		{
+			name:     "Token with role",
+			token:    adminToken,
+			wantRole: "admin",
+			wantErr:  false,
+		},
+		{
+			name:     "Token without role",
+			token:    plainToken,
+			wantRole: "",
+			wantErr:  false,
+		},
+		{
+			name:     "Invalid token",
+			token:    "ThisIsNotARealToken",
+			wantRole: "",
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, role, err := ValidateJWTWithRole(test.token, tokenSecret)
+			if (err != nil) != test.wantErr {
+				t.Errorf("ValidateJWTWithRole() error = %v, wantErr %v", err, test.wantErr)
+			}
+
+			if test.wantErr {
+				return
+			}
+
+			if result != userID {
+				t.Errorf("ValidateJWTWithRole() expects %v, got %v", userID, result)
+			}
+
+			if role != test.wantRole {
+				t.Errorf("ValidateJWTWithRole() expects role %q, got %q", test.wantRole, role)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+
+	if err := store.Put(ctx, "media/a", strings.NewReader("first")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if err := store.Put(ctx, "media/a", strings.NewReader("second")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	r, err := store.Get(ctx, "media/a")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+
+	if string(data) != "second" {
+		t.Errorf("Get() expects %q, got %q", "second", data)
+	}
+
+	if err := store.Delete(ctx, "media/a"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if _, err := store.Get(ctx, "media/a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := store.Delete(ctx, "media/a"); err != nil {
+		t.Errorf("Delete() of missing blob error = %v", err)
+	}
+}
+
+func TestFileStoreInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{
+			name: "Empty key",
+			key:  "",
+		},
+		{
+			name: "Absolute path",
+			key:  "/etc/passwd",
+		},
+		{
+			name: "Parent directory",
+			key:  "../outside",
+		},
+		{
+			name: "Nested parent directory",
+			key:  "media/../../outside",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := store.Put(ctx, test.key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Put() error = %v, want %v", err, ErrInvalidKey)
+			}
+
+			if _, err := store.Get(ctx, test.key); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Get() error = %v, want %v", err, ErrInvalidKey)
+			}
+		})
+	}
+}
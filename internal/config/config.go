@@ -0,0 +1,318 @@
+// Package config loads the server's settings. Each setting is read from, in
+// increasing order of precedence, its default, a YAML config file, an
+// environment variable and a command-line flag. A setting named read_timeout
+// is read_timeout in the file, READ_TIMEOUT in the environment and
+// -read-timeout on the command line.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting, already parsed.
+type Config struct {
+	DBURL           string
+	TokenSecret     string
+	PolkaKey        string
+	Platform        string
+	LogLevel        string
+	TracesExporter  string
+	FixturesFile    string
+	MediaDir        string
+	ChirpEditWindow time.Duration
+	AutoMigrate     bool
+
+	ListenAddr        string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownDelay     time.Duration
+	ShutdownTimeout   time.Duration
+}
+
+// Default returns the settings used when nothing overrides them.
+func Default() *Config {
+	return &Config{
+		LogLevel:        "info",
+		TracesExporter:  "none",
+		FixturesFile:    "fixtures.json",
+		MediaDir:        "media",
+		ChirpEditWindow: 15 * time.Minute,
+
+		ListenAddr:        ":8080",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownTimeout:   30 * time.Second,
+	}
+}
+
+// setting describes one field of Config. Secrets cannot be passed as flags,
+// where they would show up in the process list, and are redacted when the
+// config is printed.
+type setting struct {
+	key    string
+	usage  string
+	secret bool
+	field  func(c *Config) any
+}
+
+var settings = []setting{
+	{"db_url", "Postgres connection URL", true, func(c *Config) any { return &c.DBURL }},
+	{"token_secret", "secret for signing access tokens", true, func(c *Config) any { return &c.TokenSecret }},
+	{"polka_key", "API key Polka uses to call the webhook", true, func(c *Config) any { return &c.PolkaKey }},
+	{"platform", `deployment platform; "dev" allows resets`, false, func(c *Config) any { return &c.Platform }},
+	{"log_level", "one of debug, info, warn or error", false, func(c *Config) any { return &c.LogLevel }},
+	{"otel_traces_exporter", "one of otlp, console or none", false, func(c *Config) any { return &c.TracesExporter }},
+	{"fixtures_file", "fixtures loaded by a development reset", false, func(c *Config) any { return &c.FixturesFile }},
+	{"media_dir", "directory for uploaded media", false, func(c *Config) any { return &c.MediaDir }},
+	{"chirp_edit_window", "how long after posting a Chirp can be edited", false, func(c *Config) any { return &c.ChirpEditWindow }},
+	{"auto_migrate", "apply pending migrations when the server starts", false, func(c *Config) any { return &c.AutoMigrate }},
+	{"listen_addr", "address to listen on", false, func(c *Config) any { return &c.ListenAddr }},
+	{"read_header_timeout", "time allowed to read request headers", false, func(c *Config) any { return &c.ReadHeaderTimeout }},
+	{"read_timeout", "time allowed to read a request", false, func(c *Config) any { return &c.ReadTimeout }},
+	{"write_timeout", "time allowed to write a response", false, func(c *Config) any { return &c.WriteTimeout }},
+	{"idle_timeout", "how long idle keep-alive connections are kept", false, func(c *Config) any { return &c.IdleTimeout }},
+	{"shutdown_delay", "how long to fail readiness checks before shutting down", false, func(c *Config) any { return &c.ShutdownDelay }},
+	{"shutdown_timeout", "time allowed for requests to finish on shutdown", false, func(c *Config) any { return &c.ShutdownTimeout }},
+}
+
+func (s setting) flagName() string { return strings.ReplaceAll(s.key, "_", "-") }
+func (s setting) envName() string  { return strings.ToUpper(s.key) }
+
+// Load reads the config for the command line args, which exclude the program
+// name. The config file is named by the -config flag or the CONFIG_FILE
+// environment variable. Load returns the arguments left after the flags, or
+// flag.ErrHelp if they asked for usage.
+func Load(args []string, getenv func(string) string) (*Config, []string, error) {
+	fs, configFile, flags := newFlagSet(getenv)
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := Default()
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value := getenv(s.envName()); value != "" {
+			if err := parse(s.field(cfg), value); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", s.envName(), err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := flags[s.key]; ok {
+			if err := parse(s.field(cfg), value); err != nil {
+				return nil, nil, fmt.Errorf("-%s: %w", s.flagName(), err)
+			}
+		}
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+// PrintFlags describes the command-line flags.
+func PrintFlags(w io.Writer) {
+	fs, _, _ := newFlagSet(func(string) string { return "" })
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// newFlagSet returns flags for every setting but the secrets, and the -config
+// flag. Flag values are collected as strings to be applied last, after the
+// file and environment.
+func newFlagSet(getenv func(string) string) (*flag.FlagSet, *string, map[string]string) {
+	fs := flag.NewFlagSet("chirpy", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	configFile := fs.String("config", getenv("CONFIG_FILE"), "YAML config file")
+	flags := map[string]string{}
+	defaults := Default()
+	for _, s := range settings {
+		if s.secret {
+			continue
+		}
+		usage := fmt.Sprintf("%s (default %s)", s.usage, format(s.field(defaults)))
+		set := func(value string) error {
+			flags[s.key] = value
+			return nil
+		}
+		if _, ok := s.field(defaults).(*bool); ok {
+			fs.BoolFunc(s.flagName(), usage, set)
+		} else {
+			fs.Func(s.flagName(), usage, set)
+		}
+	}
+
+	return fs, configFile, flags
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]string
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	for key, value := range values {
+		s, ok := lookup(key)
+		if !ok {
+			return fmt.Errorf("%s: unknown setting %q", path, key)
+		}
+		if err := parse(s.field(c), value); err != nil {
+			return fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+	}
+
+	return nil
+}
+
+func lookup(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// Validate reports every problem with the config needed to run the server.
+func (c *Config) Validate() error {
+	var errs []error
+
+	for _, s := range settings {
+		if s.secret && *s.field(c).(*string) == "" {
+			errs = append(errs, fmt.Errorf("%s must be set", s.envName()))
+		}
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL: %w", err))
+	}
+
+	switch c.TracesExporter {
+	case "otlp", "console", "none":
+	default:
+		errs = append(errs, fmt.Errorf("OTEL_TRACES_EXPORTER: unknown exporter %q", c.TracesExporter))
+	}
+
+	if c.ChirpEditWindow < 0 {
+		errs = append(errs, errors.New("CHIRP_EDIT_WINDOW must not be negative"))
+	}
+
+	if c.ShutdownDelay < 0 {
+		errs = append(errs, errors.New("SHUTDOWN_DELAY must not be negative"))
+	}
+
+	for _, s := range settings {
+		if d, ok := s.field(c).(*time.Duration); ok && strings.HasSuffix(s.key, "_timeout") && *d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", s.envName()))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Print writes the config as YAML, in the format of a config file, with
+// secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range settings {
+		doc.Content = append(doc.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: s.key},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: c.display(s)},
+		)
+	}
+
+	enc := yaml.NewEncoder(w)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// LogValue logs the config with secrets redacted.
+func (c *Config) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(settings))
+	for _, s := range settings {
+		attrs = append(attrs, slog.String(s.key, c.display(s)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// display formats a setting for output. A database URL keeps everything but
+// its password; other secrets are hidden entirely, though an unset secret is
+// shown as empty.
+func (c *Config) display(s setting) string {
+	value := format(s.field(c))
+	if !s.secret || value == "" {
+		return value
+	}
+
+	if s.key == "db_url" {
+		if u, err := url.Parse(value); err == nil && u.Scheme != "" {
+			return u.Redacted()
+		}
+	}
+
+	return "REDACTED"
+}
+
+func parse(field any, value string) error {
+	switch p := field.(type) {
+	case *string:
+		*p = value
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*p = d
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*p = b
+	default:
+		panic(fmt.Sprintf("config: unsupported field type %T", field))
+	}
+	return nil
+}
+
+func format(field any) string {
+	switch p := field.(type) {
+	case *string:
+		return *p
+	case *time.Duration:
+		return p.String()
+	case *bool:
+		return strconv.FormatBool(*p)
+	default:
+		panic(fmt.Sprintf("config: unsupported field type %T", field))
+	}
+}
@@ -12,11 +12,94 @@ import (
 )
 
 type Chirp struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Body         string
+	UserID       uuid.UUID
+	SearchVector interface{}
+	ReplyToID    uuid.NullUUID
+	DeletedAt    sql.NullTime
+	LikeCount    int32
+	RechirpOfID  uuid.NullUUID
+	QuoteOfID    uuid.NullUUID
+}
+
+type ChirpAttachment struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	ChirpID     uuid.UUID
+	Position    int32
+	ContentType string
+	Width       int32
+	Height      int32
+}
+
+type ChirpFlag struct {
+	ChirpID   uuid.UUID
+	ListName  string
+	Word      string
+	CreatedAt time.Time
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	HashtagID uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID     uuid.UUID
+	UserID      uuid.UUID
+	StartOffset int32
+	EndOffset   int32
+	CreatedAt   time.Time
+}
+
+type ChirpRevision struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
+	ChirpID   uuid.UUID
 	Body      string
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Hashtag struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Tag       string
+}
+
+type ModerationAction struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	ModeratorID uuid.UUID
+	ReportID    uuid.UUID
+	Action      string
+	ChirpID     uuid.NullUUID
+	UserID      uuid.UUID
+	Note        string
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
 	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	Type      string
+	ChirpID   uuid.NullUUID
+	ReadAt    sql.NullTime
 }
 
 type RefreshToken struct {
@@ -28,6 +111,17 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime
 }
 
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ReporterID uuid.NullUUID
+	ChirpID    uuid.NullUUID
+	UserID     uuid.UUID
+	Reason     string
+	Status     string
+	ResolvedAt sql.NullTime
+}
+
 type User struct {
 	ID             uuid.UUID
 	CreatedAt      time.Time
@@ -35,4 +129,23 @@ type User struct {
 	Email          string
 	HashedPassword string
 	IsChirpyRed    bool
+	Username       sql.NullString
+	DisplayName    string
+	Bio            string
+	AvatarUrl      string
+	SuspendedAt    sql.NullTime
+	Role           string
+}
+
+type WordList struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Name      string
+	Action    string
+}
+
+type WordListWord struct {
+	WordListID uuid.UUID
+	Word       string
 }
@@ -0,0 +1,133 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxHashtagLength  = 64
+	maxUsernameLength = 15
+)
+
+// Mention is an @username reference in a Chirp body. Start and End are
+// character (not byte) offsets of the whole token, including the '@'.
+type Mention struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// Hashtags returns the distinct hashtags in body, lowercased and without the
+// leading '#', in the order they first appear. A hashtag must start at the
+// beginning of body or after a character that cannot be part of a tag, and
+// must contain at least one letter.
+func Hashtags(body string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRuneInString(body[i:])
+		if r != '#' || !isBoundary(body, i) {
+			i += size
+			continue
+		}
+
+		end := i + size
+		hasLetter := false
+		for end < len(body) {
+			r, size := utf8.DecodeRuneInString(body[end:])
+			if !isTagRune(r) {
+				break
+			}
+			hasLetter = hasLetter || unicode.IsLetter(r)
+			end += size
+		}
+
+		tag := strings.ToLower(body[i+size : end])
+		if hasLetter && utf8.RuneCountInString(tag) <= maxHashtagLength && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+
+		i = end
+	}
+
+	return tags
+}
+
+// NormalizeHashtag lowercases tag and strips a leading '#'. It returns false
+// if what remains is not a valid hashtag.
+func NormalizeHashtag(tag string) (string, bool) {
+	tag = strings.TrimPrefix(tag, "#")
+
+	tags := Hashtags("#" + tag)
+	if len(tags) != 1 || tags[0] != strings.ToLower(tag) {
+		return "", false
+	}
+
+	return tags[0], true
+}
+
+// Mentions returns every @username token in body in order of appearance.
+// Usernames are returned as written; callers resolve them case-insensitively.
+func Mentions(body string) []Mention {
+	mentions := []Mention{}
+
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || !isBoundary(body, len(string(runes[:i]))) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isUsernameRune(runes[end]) {
+			end++
+		}
+
+		username := string(runes[i+1 : end])
+		if IsUsername(username) && (end == len(runes) || !isTagRune(runes[end])) {
+			mentions = append(mentions, Mention{Username: username, Start: i, End: end})
+		}
+
+		i = end - 1
+	}
+
+	return mentions
+}
+
+// IsUsername reports whether s can be used as a username, which is anything
+// Mentions would recognise after an '@'.
+func IsUsername(s string) bool {
+	if s == "" || len(s) > maxUsernameLength {
+		return false
+	}
+
+	for _, r := range s {
+		if !isUsernameRune(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isUsernameRune(r rune) bool {
+	return r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')
+}
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// isBoundary reports whether the character before index i allows an entity
+// to start at i.
+func isBoundary(body string, i int) bool {
+	if i == 0 {
+		return true
+	}
+
+	r, _ := utf8.DecodeLastRuneInString(body[:i])
+	return !isTagRune(r) && r != '#' && r != '@'
+}
@@ -0,0 +1,128 @@
+package entities
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "No hashtags",
+			body: "Just a regular Chirp",
+			want: []string{},
+		},
+		{
+			name: "Single hashtag",
+			body: "Learning #Go today",
+			want: []string{"go"},
+		},
+		{
+			name: "Punctuation ends a hashtag",
+			body: "#boots, #dev! (#chirpy)",
+			want: []string{"boots", "dev", "chirpy"},
+		},
+		{
+			name: "Duplicates are removed",
+			body: "#go #Go #GO",
+			want: []string{"go"},
+		},
+		{
+			name: "Numbers only are not hashtags",
+			body: "Issue #123",
+			want: []string{},
+		},
+		{
+			name: "Hashtag inside a word is ignored",
+			body: "C#sharp and a#b",
+			want: []string{},
+		},
+		{
+			name: "Unicode letters",
+			body: "#café time",
+			want: []string{"café"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Hashtags(tt.body)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Hashtags() expects %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	tests := []struct {
+		name   string
+		tag    string
+		want   string
+		wantOk bool
+	}{
+		{name: "Plain tag", tag: "Go", want: "go", wantOk: true},
+		{name: "Leading hash", tag: "#Go", want: "go", wantOk: true},
+		{name: "Empty tag", tag: "", want: "", wantOk: false},
+		{name: "Invalid characters", tag: "go-lang", want: "", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NormalizeHashtag(tt.tag)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("NormalizeHashtag() expects (%v, %v), got (%v, %v)", tt.want, tt.wantOk, got, ok)
+			}
+		})
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Mention
+	}{
+		{
+			name: "No mentions",
+			body: "Nobody here",
+			want: []Mention{},
+		},
+		{
+			name: "Mentions with offsets",
+			body: "Hi @alice and @Bob_2!",
+			want: []Mention{
+				{Username: "alice", Start: 3, End: 9},
+				{Username: "Bob_2", Start: 14, End: 20},
+			},
+		},
+		{
+			name: "Offsets count characters not bytes",
+			body: "Café @zoë @zed",
+			want: []Mention{{Username: "zed", Start: 10, End: 14}},
+		},
+		{
+			name: "Email addresses are not mentions",
+			body: "mail me at bob@example.com",
+			want: []Mention{},
+		},
+		{
+			name: "Too long",
+			body: "@abcdefghijklmnop",
+			want: []Mention{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mentions(tt.body)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Mentions() expects %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Package media validates uploaded images and prepares them for serving.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+const (
+	// MaxImageBytes is the largest upload accepted for a single image.
+	MaxImageBytes = 5 << 20
+
+	// MaxImagePixels bounds the decoded size of an image, so a small file
+	// cannot claim huge dimensions and exhaust memory when decoded.
+	MaxImagePixels = 25_000_000
+
+	// ThumbnailSize is the length of a thumbnail's longest side.
+	ThumbnailSize = 320
+
+	jpegQuality = 90
+)
+
+var (
+	ErrTooLarge        = errors.New("image is too large")
+	ErrUnsupportedType = errors.New("image must be a JPEG, PNG or GIF")
+)
+
+// Image is an uploaded image that has been re-encoded without any of its
+// original metadata, along with a thumbnail in the same format.
+type Image struct {
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+	Thumbnail   []byte
+}
+
+// Process validates data as an image and re-encodes it. Decoding and
+// re-encoding drops EXIF and any other embedded metadata, such as location;
+// the EXIF orientation of JPEGs is applied to the pixels first so photos
+// keep the right way up. Animated GIFs keep only their first frame.
+func Process(data []byte) (Image, error) {
+	if len(data) > MaxImageBytes {
+		return Image{}, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return Image{}, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrUnsupportedType
+	}
+
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxImagePixels {
+		return Image{}, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrUnsupportedType
+	}
+
+	if contentType == "image/jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	encoded, err := encode(img, contentType)
+	if err != nil {
+		return Image{}, err
+	}
+
+	thumbnail, err := encode(thumbnail(img), contentType)
+	if err != nil {
+		return Image{}, err
+	}
+
+	bounds := img.Bounds()
+
+	return Image{
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Data:        encoded,
+		Thumbnail:   thumbnail,
+	}, nil
+}
+
+// thumbnail scales img so its longest side is at most ThumbnailSize.
+func thumbnail(img image.Image) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	if w <= ThumbnailSize && h <= ThumbnailSize {
+		return img
+	}
+
+	if w >= h {
+		h = max(1, h*ThumbnailSize/w)
+		w = ThumbnailSize
+	} else {
+		w = max(1, w*ThumbnailSize/h)
+		h = ThumbnailSize
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+
+	return dst
+}
+
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	case "image/png":
+		err = png.Encode(&buf, img)
+	case "image/gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		err = ErrUnsupportedType
+	}
+
+	return buf.Bytes(), err
+}
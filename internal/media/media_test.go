@@ -0,0 +1,218 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		wantErr       error
+		contentType   string
+		width, height int
+		thumbW        int
+		thumbH        int
+	}{
+		{
+			name:        "Large PNG is thumbnailed",
+			data:        encodePNG(t, 800, 400),
+			contentType: "image/png",
+			width:       800,
+			height:      400,
+			thumbW:      320,
+			thumbH:      160,
+		},
+		{
+			name:        "Small JPEG keeps its size",
+			data:        encodeJPEG(t, 100, 50),
+			contentType: "image/jpeg",
+			width:       100,
+			height:      50,
+			thumbW:      100,
+			thumbH:      50,
+		},
+		{
+			name:        "Tall GIF",
+			data:        encodeGIF(t, 200, 640),
+			contentType: "image/gif",
+			width:       200,
+			height:      640,
+			thumbW:      100,
+			thumbH:      320,
+		},
+		{
+			name:    "Not an image",
+			data:    []byte("hello, world"),
+			wantErr: ErrUnsupportedType,
+		},
+		{
+			name:    "Too many bytes",
+			data:    make([]byte, MaxImageBytes+1),
+			wantErr: ErrTooLarge,
+		},
+		{
+			name:    "Too many pixels",
+			data:    pngHeader(10000, 10000),
+			wantErr: ErrTooLarge,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img, err := Process(test.data)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Process() error = %v, wantErr %v", err, test.wantErr)
+			}
+
+			if test.wantErr != nil {
+				return
+			}
+
+			if img.ContentType != test.contentType {
+				t.Errorf("Process() content type = %s, want %s", img.ContentType, test.contentType)
+			}
+
+			if img.Width != test.width || img.Height != test.height {
+				t.Errorf("Process() size = %dx%d, want %dx%d", img.Width, img.Height, test.width, test.height)
+			}
+
+			thumb, _, err := image.DecodeConfig(bytes.NewReader(img.Thumbnail))
+			if err != nil {
+				t.Fatalf("decoding thumbnail: %v", err)
+			}
+
+			if thumb.Width != test.thumbW || thumb.Height != test.thumbH {
+				t.Errorf("Process() thumbnail = %dx%d, want %dx%d", thumb.Width, thumb.Height, test.thumbW, test.thumbH)
+			}
+		})
+	}
+}
+
+func TestProcessStripsEXIF(t *testing.T) {
+	// A 40x20 JPEG tagged as needing a 90° clockwise rotation.
+	data := withEXIFOrientation(encodeJPEG(t, 40, 20), 6)
+
+	img, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if img.Width != 20 || img.Height != 40 {
+		t.Errorf("Process() size = %dx%d, want 20x40", img.Width, img.Height)
+	}
+
+	if bytes.Contains(img.Data, []byte("Exif")) {
+		t.Errorf("Process() output still contains EXIF data")
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 2x1 image: red on the left, blue on the right.
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.RGBA{R: 255, A: 255})
+	src.Set(1, 0, color.RGBA{B: 255, A: 255})
+
+	red := color.RGBA{R: 255, A: 255}
+
+	tests := []struct {
+		name        string
+		orientation int
+		redAt       image.Point
+	}{
+		{name: "Normal", orientation: 1, redAt: image.Pt(0, 0)},
+		{name: "Mirrored", orientation: 2, redAt: image.Pt(1, 0)},
+		{name: "Rotated 180", orientation: 3, redAt: image.Pt(1, 0)},
+		{name: "Rotate clockwise", orientation: 6, redAt: image.Pt(0, 0)},
+		{name: "Rotate counter-clockwise", orientation: 8, redAt: image.Pt(0, 1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := orient(src, test.orientation)
+
+			if c := color.RGBAModel.Convert(got.At(test.redAt.X, test.redAt.Y)); c != red {
+				t.Errorf("orient() pixel at %v = %v, want red", test.redAt, c)
+			}
+		})
+	}
+}
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(w, h)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(w, h), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, testImage(w, h), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngHeader returns the start of a PNG claiming the given dimensions, which
+// is all that is needed to read its config.
+func pngHeader(w, h int) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(w))
+	binary.BigEndian.PutUint32(ihdr[8:], uint32(h))
+	ihdr[12] = 8 // Bit depth.
+	ihdr[13] = 6 // RGBA.
+
+	data := []byte("\x89PNG\r\n\x1a\n")
+	data = binary.BigEndian.AppendUint32(data, 13)
+	data = append(data, ihdr...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(ihdr))
+}
+
+// withEXIFOrientation inserts an APP1 segment holding only an orientation tag
+// after the start-of-image marker of a JPEG.
+func withEXIFOrientation(jpg []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3) // SHORT.
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, 0xFF, 0xE1)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
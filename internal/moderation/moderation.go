@@ -0,0 +1,241 @@
+// Package moderation checks Chirp bodies against word lists. Words are
+// matched whole, after folding case, accents, look-alike characters and
+// common leetspeak substitutions, so "Kerfuffle!", "kérfuffle" and
+// "k3rfuffl3" all match "kerfuffle".
+package moderation
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Action is what happens to a Chirp containing a word from a list.
+type Action string
+
+const (
+	// Mask replaces the word with asterisks.
+	Mask Action = "mask"
+	// Reject refuses the whole Chirp.
+	Reject Action = "reject"
+	// Flag accepts the Chirp but queues it for review by a moderator.
+	Flag Action = "flag"
+)
+
+const mask = "****"
+
+// IsValid reports whether a is one of the known actions.
+func (a Action) IsValid() bool {
+	return a == Mask || a == Reject || a == Flag
+}
+
+// List is a named set of words that share an action.
+type List struct {
+	Name   string
+	Action Action
+	Words  []string
+}
+
+// Match is a word in a body that matched a list.
+type Match struct {
+	List   string
+	Action Action
+	Word   string
+}
+
+// Result is the outcome of checking a body. Body has masked words replaced;
+// it is only meaningful when Rejected is false.
+type Result struct {
+	Body     string
+	Rejected bool
+	Flagged  bool
+	Matches  []Match
+}
+
+type entry struct {
+	list     string
+	action   Action
+	word     string
+	squeezed string
+}
+
+// Filter matches text against a fixed set of lists. Build one with
+// NewFilter; the zero value matches nothing.
+type Filter struct {
+	words map[string][]entry
+	// squeezed indexes entries by their form with repeated letters
+	// collapsed, to catch stretched words such as "kerrrfuffle".
+	squeezed map[string][]entry
+}
+
+// NewFilter compiles lists into a Filter. Words that normalize to nothing
+// are ignored.
+func NewFilter(lists []List) *Filter {
+	f := &Filter{words: map[string][]entry{}, squeezed: map[string][]entry{}}
+
+	for _, list := range lists {
+		for _, word := range list.Words {
+			normalized := Normalize(word)
+			if normalized == "" {
+				continue
+			}
+
+			e := entry{list: list.Name, action: list.Action, word: normalized, squeezed: squeeze(normalized)}
+			f.words[normalized] = append(f.words[normalized], e)
+			f.squeezed[e.squeezed] = append(f.squeezed[e.squeezed], e)
+		}
+	}
+
+	return f
+}
+
+// Check matches each whitespace-separated word of body against the filter.
+// Punctuation around a word is kept when it is masked, and whitespace is
+// preserved exactly.
+func (f *Filter) Check(body string) Result {
+	result := Result{}
+
+	var b strings.Builder
+	for len(body) > 0 {
+		end := strings.IndexFunc(body, unicode.IsSpace)
+		if end == 0 {
+			_, size := utf8.DecodeRuneInString(body)
+			b.WriteString(body[:size])
+			body = body[size:]
+			continue
+		}
+		if end < 0 {
+			end = len(body)
+		}
+
+		token := body[:end]
+		body = body[end:]
+
+		prefix, core, suffix := splitPunctuation(token)
+		entries := f.match(core)
+		if len(entries) == 0 {
+			b.WriteString(token)
+			continue
+		}
+
+		masked := false
+		for _, e := range entries {
+			result.Matches = append(result.Matches, Match{List: e.list, Action: e.action, Word: e.word})
+
+			switch e.action {
+			case Mask:
+				masked = true
+			case Reject:
+				result.Rejected = true
+			case Flag:
+				result.Flagged = true
+			}
+		}
+
+		if masked {
+			b.WriteString(prefix + mask + suffix)
+		} else {
+			b.WriteString(token)
+		}
+	}
+
+	result.Body = b.String()
+
+	return result
+}
+
+func (f *Filter) match(core string) []entry {
+	if f == nil || f.words == nil {
+		return nil
+	}
+
+	normalized := Normalize(core)
+	if normalized == "" {
+		return nil
+	}
+
+	if entries := f.words[normalized]; len(entries) > 0 {
+		return entries
+	}
+
+	// Only accept a stretched match when the text is longer than the listed
+	// word, so "as" does not match a listed "ass".
+	var stretched []entry
+	for _, e := range f.squeezed[squeeze(normalized)] {
+		if len(normalized) > len(e.word) {
+			stretched = append(stretched, e)
+		}
+	}
+
+	return stretched
+}
+
+// leet maps characters commonly substituted for letters to those letters.
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'9': 'g',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'l',
+	'+': 't',
+}
+
+// Normalize reduces a word to the form used for matching: compatibility
+// characters are folded (so full-width letters become ASCII), accents are
+// dropped, case is folded, leetspeak is decoded and anything that is still
+// not a letter is removed.
+func Normalize(word string) string {
+	var b strings.Builder
+
+	for _, r := range norm.NFKD.String(word) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		if l, ok := leet[r]; ok {
+			r = l
+		}
+
+		if unicode.IsLetter(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+
+	return b.String()
+}
+
+// splitPunctuation separates leading and trailing punctuation from a token.
+// Symbols such as '$' are left in place because they may stand for letters.
+func splitPunctuation(token string) (prefix, core, suffix string) {
+	core = strings.TrimLeftFunc(token, unicode.IsPunct)
+	prefix = token[:len(token)-len(core)]
+
+	trimmed := strings.TrimRightFunc(core, unicode.IsPunct)
+	suffix = core[len(trimmed):]
+
+	return prefix, trimmed, suffix
+}
+
+// squeeze collapses runs of the same rune into one.
+func squeeze(s string) string {
+	var b strings.Builder
+
+	var last rune = -1
+	for _, r := range s {
+		if r != last {
+			b.WriteRune(r)
+		}
+		last = r
+	}
+
+	return b.String()
+}
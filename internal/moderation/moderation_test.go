@@ -0,0 +1,127 @@
+package moderation
+
+import (
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	filter := NewFilter([]List{
+		{Name: "default", Action: Mask, Words: []string{"kerfuffle", "sharbert", "fornax"}},
+		{Name: "slurs", Action: Reject, Words: []string{"grawlix"}},
+		{Name: "watch", Action: Flag, Words: []string{"scam"}},
+		{Name: "short", Action: Mask, Words: []string{"ass"}},
+	})
+
+	tests := []struct {
+		name     string
+		body     string
+		want     string
+		rejected bool
+		flagged  bool
+	}{
+		{
+			name: "Clean",
+			body: "This is a nice Chirp",
+			want: "This is a nice Chirp",
+		},
+		{
+			name: "Plain word",
+			body: "This is a kerfuffle opinion",
+			want: "This is a **** opinion",
+		},
+		{
+			name: "Case and punctuation",
+			body: "Kerfuffle! What a sharbert.",
+			want: "****! What a ****.",
+		},
+		{
+			name: "Quoted",
+			body: `He said "fornax"`,
+			want: `He said "****"`,
+		},
+		{
+			name: "Leetspeak",
+			body: "k3rfuffl3 and $h@rb3rt and f0rn4x",
+			want: "**** and **** and ****",
+		},
+		{
+			name: "Accents and full-width letters",
+			body: "kérfuffle ｆｏｒｎａｘ",
+			want: "**** ****",
+		},
+		{
+			name: "Stretched",
+			body: "kerrrfuffle",
+			want: "****",
+		},
+		{
+			name: "Shorter word is not stretched",
+			body: "as if",
+			want: "as if",
+		},
+		{
+			name: "Part of a longer word",
+			body: "kerfufflement",
+			want: "kerfufflement",
+		},
+		{
+			name: "Whitespace is preserved",
+			body: "a  kerfuffle\tb\n",
+			want: "a  ****\tb\n",
+		},
+		{
+			name:     "Rejected",
+			body:     "what a gr4wlix",
+			want:     "what a gr4wlix",
+			rejected: true,
+		},
+		{
+			name:    "Flagged",
+			body:    "not a SCAM!",
+			want:    "not a SCAM!",
+			flagged: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := filter.Check(test.body)
+
+			if result.Body != test.want {
+				t.Errorf("Check() body = %q, want %q", result.Body, test.want)
+			}
+
+			if result.Rejected != test.rejected {
+				t.Errorf("Check() rejected = %v, want %v", result.Rejected, test.rejected)
+			}
+
+			if result.Flagged != test.flagged {
+				t.Errorf("Check() flagged = %v, want %v", result.Flagged, test.flagged)
+			}
+		})
+	}
+}
+
+func TestCheckMatches(t *testing.T) {
+	filter := NewFilter([]List{
+		{Name: "watch", Action: Flag, Words: []string{"Scam"}},
+	})
+
+	result := filter.Check("scam scam")
+	if len(result.Matches) != 2 {
+		t.Fatalf("Check() matches = %d, want 2", len(result.Matches))
+	}
+
+	want := Match{List: "watch", Action: Flag, Word: "scam"}
+	if result.Matches[0] != want {
+		t.Errorf("Check() match = %+v, want %+v", result.Matches[0], want)
+	}
+}
+
+func TestZeroFilter(t *testing.T) {
+	var filter *Filter
+
+	if result := filter.Check("kerfuffle"); result.Body != "kerfuffle" || len(result.Matches) != 0 {
+		t.Errorf("Check() on nil Filter = %+v, want no matches", result)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) handlerLikeChirp(w http.ResponseWriter, req *http.Request) {
+	cfg.setChirpLike(w, req, true)
+}
+
+func (cfg *apiConfig) handlerUnlikeChirp(w http.ResponseWriter, req *http.Request) {
+	cfg.setChirpLike(w, req, false)
+}
+
+func (cfg *apiConfig) setChirpLike(w http.ResponseWriter, req *http.Request, liked bool) {
+	chirpID := req.PathValue("chirpID")
+	chirpUUID, err := uuid.Parse(chirpID)
+	if err != nil {
+		logger(req.Context()).Warn("Error parsing Chirp ID", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID")
+		return
+	}
+
+	userID, ok := cfg.getActiveUserIDFromRequest(w, req)
+	if !ok {
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirp(req.Context(), chirpUUID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting Chirp from DB", "error", err)
+
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusNotFound, "Chirp not found")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirp")
+		}
+		return
+	}
+
+	if dbChirp.DeletedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	if err := cfg.updateChirpLike(req.Context(), userID, dbChirp, liked); err != nil {
+		logger(req.Context()).Error("Error updating Chirp like", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Error updating like")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// updateChirpLike records or removes a like and keeps the Chirp's like_count
+// in step within the same transaction, notifying the author of new likes.
+// Repeated likes or unlikes are no-ops.
+func (cfg *apiConfig) updateChirpLike(ctx context.Context, userID uuid.UUID, dbChirp database.Chirp, liked bool) error {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.withTx(tx)
+
+	var changed int64
+	var delta int32
+	if liked {
+		params := database.CreateChirpLikeParams{UserID: userID, ChirpID: dbChirp.ID}
+		changed, err = qtx.CreateChirpLike(ctx, params)
+		delta = 1
+	} else {
+		params := database.DeleteChirpLikeParams{UserID: userID, ChirpID: dbChirp.ID}
+		changed, err = qtx.DeleteChirpLike(ctx, params)
+		delta = -1
+	}
+	if err != nil {
+		return err
+	}
+
+	if changed == 0 {
+		return nil
+	}
+
+	params := database.AdjustChirpLikeCountParams{Delta: delta, ID: dbChirp.ID}
+	if err := qtx.AdjustChirpLikeCount(ctx, params); err != nil {
+		return err
+	}
+
+	if liked {
+		chirpID := uuid.NullUUID{UUID: dbChirp.ID, Valid: true}
+		if err := notify(ctx, qtx, dbChirp.UserID, userID, notificationLike, chirpID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// markLikedChirps sets LikedByMe on each of chirps liked by viewerID using a
+// single query. It does nothing for anonymous viewers.
+func (cfg *apiConfig) markLikedChirps(ctx context.Context, viewerID uuid.NullUUID, chirps []Chirp) error {
+	if !viewerID.Valid || len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	params := database.GetLikedChirpIDsParams{UserID: viewerID.UUID, ChirpIds: chirpIDs}
+	likedIDs, err := cfg.db.GetLikedChirpIDs(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	liked := make(map[uuid.UUID]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+
+	for i := range chirps {
+		chirps[i].LikedByMe = liked[chirps[i].ID]
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestUserKey
+)
+
+// logger returns the request-scoped logger stored in ctx by
+// middlewareLogging, or the default logger outside of a request.
+func logger(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// setRequestUser records the authenticated user so the request log line can
+// include it. It does nothing outside of middlewareLogging.
+func setRequestUser(ctx context.Context, userID uuid.UUID) {
+	if u, ok := ctx.Value(requestUserKey).(*uuid.NullUUID); ok {
+		*u = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+}
+
+// middlewareLogging gives every request an ID, taken from the X-Request-ID
+// header when the client or a proxy supplied a usable one, and echoes it in
+// the response. Handlers get a logger carrying the ID, and the trace ID when
+// the request is traced, through logger, and a line is logged for each
+// request once it has been handled.
+func middlewareLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		requestID := req.Header.Get(requestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		l := slog.Default().With("request_id", requestID)
+		if spanContext := trace.SpanContextFromContext(req.Context()); spanContext.IsValid() {
+			l = l.With("trace_id", spanContext.TraceID().String())
+		}
+		user := &uuid.NullUUID{}
+
+		ctx := context.WithValue(req.Context(), loggerKey, l)
+		ctx = context.WithValue(ctx, requestUserKey, user)
+		orig := req
+		req = req.WithContext(ctx)
+
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, req)
+
+		// The mux sets the matched pattern on our copy of the request. Pass it
+		// back so the tracing middleware can name the span after the route.
+		orig.Pattern = req.Pattern
+
+		attrs := []any{
+			"method", req.Method,
+			"route", req.Pattern,
+			"status", rec.statusCode(),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"bytes", rec.size,
+		}
+		if user.Valid {
+			attrs = append(attrs, "user_id", user.UUID)
+		}
+
+		l.Info("request", attrs...)
+	})
+}
+
+// isValidRequestID accepts IDs of printable ASCII that are short enough to be
+// safe to log and echo back.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newLogger returns a JSON logger writing to stdout at the given level, one
+// of debug, info, warn or error. An empty level means info.
+func newLogger(level string) (*slog.Logger, error) {
+	var l slog.Level
+	if level != "" {
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return nil, err
+		}
+	}
+
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: l})), nil
+}
+
+// fatal logs msg at error level and exits, like log.Fatal.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
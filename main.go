@@ -1,56 +1,158 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/keithcrooks/chirpy/internal/blobstore"
+	"github.com/keithcrooks/chirpy/internal/config"
 	"github.com/keithcrooks/chirpy/internal/database"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"github.com/pressly/goose/v3"
 )
 
 type apiConfig struct {
-	db             *database.Queries
-	fileserverHits atomic.Int32
-	polkaKey       string
-	tokenSecret    string
+	db                 *database.Queries
+	sqlDB              *sql.DB
+	fileserverHits     atomic.Int32
+	polkaKey           string
+	platform           string
+	fixturesFile       string
+	tokenSecret        string
+	chirpEditWindow    time.Duration
+	blobs              blobstore.BlobStore
+	metrics            *serverMetrics
+	migrator           *goose.Provider
+	shuttingDown       atomic.Bool
+	chirpStream        *broker[Chirp]
+	notificationStream *broker[notificationEvent]
+}
+
+// withTx returns queries that run in tx and, like cfg.db, are instrumented.
+func (cfg *apiConfig) withTx(tx *sql.Tx) *database.Queries {
+	return database.New(cfg.metrics.instrumentDB(tx))
 }
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatalf("Error loading .env file: %s", err)
+	// The .env file is a convenience for development and may be missing.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fatal("Error loading .env file", "error", err)
 	}
 
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
-		log.Fatal("DB_URL must be set")
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		printUsage(os.Stdout)
+		return
+	}
+	if err != nil {
+		fatal("Error loading config", "error", err)
 	}
 
-	db, err := sql.Open("postgres", dbURL)
+	defaultLogger, err := newLogger(cfg.LogLevel)
 	if err != nil {
-		log.Fatalf("Error connecting to the database: %s", err)
+		fatal("Error parsing LOG_LEVEL", "error", err)
 	}
+	slog.SetDefault(defaultLogger)
 
-	tokenSecret := os.Getenv("TOKEN_SECRET")
-	if tokenSecret == "" {
-		log.Fatal("TOKEN_SECRET must be set")
+	if len(args) == 0 || args[0] == "serve" {
+		if len(args) > 1 {
+			fatal("Unexpected arguments to serve", "args", args[1:])
+		}
+		serve(cfg)
+		return
 	}
 
-	polkaKey := os.Getenv("POLKA_KEY")
-	if tokenSecret == "" {
-		log.Fatal("POLKA_KEY must be set")
+	if err := runCommand(context.Background(), cfg, args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// serve runs the server until it is signalled to stop, then exits.
+func serve(cfg *config.Config) {
+	if err := cfg.Validate(); err != nil {
+		fatal("Invalid config", "error", err)
+	}
+	slog.Info("Loaded config", "config", cfg)
+
+	db, err := sql.Open("postgres", cfg.DBURL)
+	if err != nil {
+		fatal("Error connecting to the database", "error", err)
+	}
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		fatal("Error reading embedded migrations", "error", err)
+	}
+
+	if cfg.AutoMigrate {
+		if err := migrateOnStartup(context.Background(), migrator); err != nil {
+			fatal("Error applying migrations", "error", err)
+		}
+	}
+
+	blobs, err := blobstore.NewFileStore(cfg.MediaDir)
+	if err != nil {
+		fatal("Error opening media store", "error", err)
+	}
+
+	shutdownTracing, err := setupTracing(context.Background(), cfg.TracesExporter)
+	if err != nil {
+		fatal("Error setting up tracing", "error", err)
 	}
 
+	metrics := newMetrics(db)
+
 	apiCfg := apiConfig{
-		db:             database.New(db),
-		fileserverHits: atomic.Int32{},
-		polkaKey:       polkaKey,
-		tokenSecret:    tokenSecret,
+		db:                 database.New(metrics.instrumentDB(db)),
+		sqlDB:              db,
+		fileserverHits:     atomic.Int32{},
+		polkaKey:           cfg.PolkaKey,
+		platform:           cfg.Platform,
+		fixturesFile:       cfg.FixturesFile,
+		tokenSecret:        cfg.TokenSecret,
+		chirpEditWindow:    cfg.ChirpEditWindow,
+		blobs:              blobs,
+		metrics:            metrics,
+		migrator:           migrator,
+		chirpStream:        newBroker[Chirp](),
+		notificationStream: newBroker[notificationEvent](),
+	}
+
+	listener := pq.NewListener(cfg.DBURL, time.Second, time.Minute, func(_ pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Error("Error listening for events", "error", err)
+		}
+	})
+	for _, channel := range []string{chirpCreatedChannel, notificationCreatedChannel} {
+		if err := listener.Listen(channel); err != nil {
+			fatal("Error listening for events", "channel", channel, "error", err)
+		}
 	}
 
+	// The first SIGINT or SIGTERM starts a graceful shutdown. stop restores
+	// the default behaviour, so a second one exits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listenerDone := make(chan struct{})
+	go func() {
+		defer close(listenerDone)
+		apiCfg.listen(ctx, listener)
+	}()
+
 	mux := http.NewServeMux()
 
 	mux.Handle(
@@ -61,24 +163,115 @@ func main() {
 		),
 	)
 	mux.HandleFunc("GET /api/chirps", apiCfg.handlerGetAllChirps)
+	mux.HandleFunc("GET /api/chirps/search", apiCfg.handlerSearchChirps)
+	mux.HandleFunc("GET /api/chirps/stream", apiCfg.handlerStreamChirps)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.handlerDeleteChirp)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.handlerGetChirp)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.handlerUpdateChirp)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.handlerGetChirpRevisions)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.handlerGetChirpThread)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.handlerLikeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.handlerUnlikeChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.handlerRechirp)
 	mux.HandleFunc("POST /api/chirps", apiCfg.handlerCreateChirp)
+	mux.HandleFunc("GET /api/hashtags/trending", apiCfg.handlerGetTrendingHashtags)
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.handlerGetHashtagChirps)
 	mux.HandleFunc("GET /api/healthz", handlerStatus)
+	mux.HandleFunc("GET /api/readyz", apiCfg.handlerReadiness)
+	mux.HandleFunc("GET /api/media/{mediaID}", apiCfg.handlerGetMedia)
+	mux.HandleFunc("GET /api/media/{mediaID}/thumbnail", apiCfg.handlerGetMediaThumbnail)
+	mux.HandleFunc("GET /api/notifications", apiCfg.handlerGetNotifications)
+	mux.HandleFunc("POST /api/notifications/read", apiCfg.handlerMarkNotificationsRead)
+	mux.HandleFunc("POST /api/reports", apiCfg.handlerCreateReport)
+	mux.HandleFunc("GET /api/timeline", apiCfg.handlerGetTimeline)
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.handlerPolkaWebhook)
 	mux.HandleFunc("POST /api/users", apiCfg.handlerAddUser)
 	mux.HandleFunc("PUT /api/users", apiCfg.handlerUpdateUser)
+	mux.HandleFunc("GET /api/users/{userID}", apiCfg.handlerGetUserProfile)
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.handlerFollowUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.handlerUnfollowUser)
+	mux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.handlerGetFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", apiCfg.handlerGetFollowing)
+	mux.HandleFunc("GET /api/users/{userID}/mentions", apiCfg.handlerGetUserMentions)
+	mux.HandleFunc("GET /api/ws", apiCfg.handlerWebSocket)
 	mux.HandleFunc("POST /api/login", apiCfg.handlerLoginUser)
 	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefresh)
 	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevoke)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
-	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
+	mux.Handle("GET /metrics", metrics.handler())
+	mux.Handle("GET /admin/metrics", apiCfg.middlewareRequireRole(roleAdmin, apiCfg.handlerMetrics))
+	mux.Handle("POST /admin/reset", apiCfg.middlewareRequireRole(roleAdmin, apiCfg.handlerReset))
+	mux.Handle("GET /admin/reports", apiCfg.middlewareRequireRole(roleModerator, apiCfg.handlerGetReports))
+	mux.Handle("GET /admin/reports/{reportID}", apiCfg.middlewareRequireRole(roleModerator, apiCfg.handlerGetReport))
+	mux.Handle("POST /admin/reports/{reportID}/actions", apiCfg.middlewareRequireRole(roleModerator, apiCfg.handlerModerateReport))
+	mux.Handle("PUT /admin/users/{userID}/role", apiCfg.middlewareRequireRole(roleAdmin, apiCfg.handlerUpdateUserRole))
+	mux.Handle("GET /admin/wordlists", apiCfg.middlewareRequireRole(roleModerator, apiCfg.handlerGetWordLists))
+	mux.Handle("POST /admin/wordlists", apiCfg.middlewareRequireRole(roleModerator, apiCfg.handlerCreateWordList))
+	mux.Handle("GET /admin/wordlists/{listID}", apiCfg.middlewareRequireRole(roleModerator, apiCfg.handlerGetWordList))
+	mux.Handle("PUT /admin/wordlists/{listID}", apiCfg.middlewareRequireRole(roleModerator, apiCfg.handlerUpdateWordList))
+	mux.Handle("DELETE /admin/wordlists/{listID}", apiCfg.middlewareRequireRole(roleModerator, apiCfg.handlerDeleteWordList))
 
+	// Streams clear the read and write deadlines for their own connections.
 	server := http.Server{
-		Handler: mux,
-		Addr:    ":8080",
+		Handler:           middlewareTracing(middlewareLogging(metrics.middleware(mux))),
+		Addr:              cfg.ListenAddr,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	// Shutdown waits for requests to finish, which streams never do on their
+	// own, so end their subscriptions.
+	server.RegisterOnShutdown(func() {
+		apiCfg.chirpStream.close()
+		apiCfg.notificationStream.close()
+	})
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", "addr", server.Addr)
+		serveErr <- server.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serveErr:
+		slog.Error("Error serving requests", "error", err)
+		exitCode = 1
+	case <-ctx.Done():
+		stop()
+		slog.Info("Shutting down server", "delay", cfg.ShutdownDelay.String(), "timeout", cfg.ShutdownTimeout.String())
+
+		// Keep serving, but fail readiness checks, so that load balancers
+		// stop sending requests before the listener closes.
+		apiCfg.shuttingDown.Store(true)
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Error draining requests", "error", err)
+		server.Close()
+		exitCode = 1
+	}
+
+	stop()
+	<-listenerDone
+	if err := listener.Close(); err != nil {
+		slog.Error("Error closing event listener", "error", err)
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
+	}
+
+	cancel()
+
+	if err := db.Close(); err != nil {
+		slog.Error("Error closing the database", "error", err)
 	}
 
-	log.Println("Starting server...")
-	log.Fatal(server.ListenAndServe())
+	slog.Info("Server stopped")
+	os.Exit(exitCode)
 }
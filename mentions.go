@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/database"
+	"github.com/keithcrooks/chirpy/internal/entities"
+)
+
+// Mention is a resolved @username in a Chirp body. Start and End are
+// character offsets into the body, covering the '@' and the username.
+type Mention struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+	Start    int32     `json:"start"`
+	End      int32     `json:"end"`
+}
+
+// handlerGetUserMentions returns the Chirps that mention a user, newest first.
+func (cfg *apiConfig) handlerGetUserMentions(w http.ResponseWriter, req *http.Request) {
+	dbUser, ok := cfg.getUserFromPath(w, req)
+	if !ok {
+		return
+	}
+
+	viewerID, err := cfg.getOptionalUserIDFromRequest(req)
+	if err != nil {
+		logger(req.Context()).Warn("Error authenticating user", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	params := database.ListMentioningChirpsParams{UserID: dbUser.ID, Limit: p.Limit + 1}
+	params.CursorCreatedAt, params.CursorID = cursorParams(p.Cursor)
+
+	dbChirps, err := cfg.db.ListMentioningChirps(req.Context(), params)
+	if err != nil {
+		logger(req.Context()).Error("Error getting mentioning Chirps from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirps")
+		return
+	}
+
+	chirps := Chirps{Entries: []Chirp{}}
+
+	dbChirps, chirps.NextCursor = trimPage(dbChirps, p.Limit, chirpCursor)
+
+	for _, dbChirp := range dbChirps {
+		chirps.Entries = append(chirps.Entries, chirpFromDB(dbChirp))
+	}
+
+	if err := cfg.decorateChirps(req.Context(), viewerID, chirps.Entries); err != nil {
+		logger(req.Context()).Error("Error decorating Chirps", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirps")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
+
+// saveChirpMentions resolves the @usernames in dbChirp's body, records the
+// ones that belong to a user and notifies those users. Unknown usernames are
+// left as plain text. It is run with transactional queries alongside the
+// write that produced the body.
+func saveChirpMentions(ctx context.Context, qtx *database.Queries, dbChirp database.Chirp) error {
+	mentions := entities.Mentions(dbChirp.Body)
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	usernames := make([]string, 0, len(mentions))
+	for _, mention := range mentions {
+		usernames = append(usernames, strings.ToLower(mention.Username))
+	}
+
+	users, err := qtx.GetUsersByUsernames(ctx, usernames)
+	if err != nil {
+		return err
+	}
+
+	userIDs := make(map[string]uuid.UUID, len(users))
+	for _, user := range users {
+		userIDs[strings.ToLower(user.Username.String)] = user.ID
+	}
+
+	for _, mention := range mentions {
+		userID, ok := userIDs[strings.ToLower(mention.Username)]
+		if !ok {
+			continue
+		}
+
+		params := database.CreateChirpMentionParams{
+			ChirpID:     dbChirp.ID,
+			UserID:      userID,
+			StartOffset: int32(mention.Start),
+			EndOffset:   int32(mention.End),
+		}
+		if err := qtx.CreateChirpMention(ctx, params); err != nil {
+			return err
+		}
+
+		chirpID := uuid.NullUUID{UUID: dbChirp.ID, Valid: true}
+		if err := notify(ctx, qtx, userID, dbChirp.UserID, notificationMention, chirpID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadChirpMentions sets Mentions on each of chirps using a single query.
+func (cfg *apiConfig) loadChirpMentions(ctx context.Context, chirps []Chirp) error {
+	for i := range chirps {
+		chirps[i].Mentions = []Mention{}
+	}
+
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	rows, err := cfg.db.GetChirpMentions(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+
+	mentions := make(map[uuid.UUID][]Mention, len(chirps))
+	for _, row := range rows {
+		mention := Mention{
+			UserID:   row.UserID,
+			Username: row.Username.String,
+			Start:    row.StartOffset,
+			End:      row.EndOffset,
+		}
+
+		mentions[row.ChirpID] = append(mentions[row.ChirpID], mention)
+	}
+
+	for i := range chirps {
+		if m, ok := mentions[chirps[i].ID]; ok {
+			chirps[i].Mentions = m
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// schemaFS holds the goose migrations from sql/schema, so that the binary
+// can apply them itself and knows which schema version it was built against.
+//
+//go:embed sql/schema/*.sql
+var schemaFS embed.FS
+
+// newMigrator returns a goose provider for the embedded migrations. It holds
+// a Postgres advisory lock while migrating, so instances that migrate on
+// startup at the same time take turns.
+func newMigrator(db *sql.DB) (*goose.Provider, error) {
+	schema, err := fs.Sub(schemaFS, "sql/schema")
+	if err != nil {
+		return nil, err
+	}
+
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+
+	return goose.NewProvider(goose.DialectPostgres, db, schema, goose.WithSessionLocker(locker))
+}
+
+// migrate runs one of the migrate subcommands against db, writing what it did
+// to w:
+//
+//   - up applies every pending migration
+//   - down rolls back the latest migration
+//   - redo rolls back the latest migration and applies it again
+//   - status lists every migration and when it was applied
+func migrate(ctx context.Context, db *sql.DB, command string, w io.Writer) error {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		results, err := migrateUp(ctx, migrator)
+		printMigrationResults(w, results...)
+		if err == nil && len(results) == 0 {
+			fmt.Fprintln(w, "No pending migrations")
+		}
+		return err
+	case "down":
+		result, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationResults(w, result)
+		return nil
+	case "redo":
+		down, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationResults(w, down)
+
+		up, err := migrator.ApplyVersion(ctx, down.Source.Version, true)
+		if err != nil {
+			return err
+		}
+		printMigrationResults(w, up)
+		return nil
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "MIGRATION\tSTATE\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := ""
+			if status.State == goose.StateApplied {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", path.Base(status.Source.Path), status.State, appliedAt)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q; want up, down, redo or status", command)
+	}
+}
+
+func printMigrationResults(w io.Writer, results ...*goose.MigrationResult) {
+	for _, result := range results {
+		fmt.Fprintln(w, result)
+	}
+}
+
+// migrateOnStartup applies pending migrations before the server starts.
+func migrateOnStartup(ctx context.Context, migrator *goose.Provider) error {
+	results, err := migrateUp(ctx, migrator)
+	for _, result := range results {
+		slog.Info("Applied migration",
+			"migration", path.Base(result.Source.Path),
+			"duration_ms", result.Duration.Milliseconds(),
+		)
+	}
+	return err
+}
+
+// migrateUp applies every pending migration. If one fails, it returns the
+// ones applied before it along with the error.
+func migrateUp(ctx context.Context, migrator *goose.Provider) ([]*goose.MigrationResult, error) {
+	results, err := migrator.Up(ctx)
+
+	var partial *goose.PartialError
+	if errors.As(err, &partial) {
+		results = partial.Applied
+	}
+
+	return results, err
+}
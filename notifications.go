@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/database"
+)
+
+// Notification types. They match the CHECK constraint on notifications.type.
+const (
+	notificationFollow  = "follow"
+	notificationLike    = "like"
+	notificationReply   = "reply"
+	notificationMention = "mention"
+)
+
+// Notification tells a user that someone else acted on them or their Chirps.
+// ChirpID is the liked Chirp for likes and the new Chirp for replies and
+// mentions; it is omitted for follows.
+type Notification struct {
+	ID            uuid.UUID  `json:"id"`
+	Type          string     `json:"type"`
+	ActorID       uuid.UUID  `json:"actor_id"`
+	ActorUsername string     `json:"actor_username,omitempty"`
+	ChirpID       *uuid.UUID `json:"chirp_id,omitempty"`
+	Read          bool       `json:"read"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type Notifications struct {
+	Entries     []Notification `json:"notifications"`
+	UnreadCount int64          `json:"unread_count"`
+	NextCursor  string         `json:"next_cursor,omitempty"`
+}
+
+// handlerGetNotifications returns the caller's notifications, newest first,
+// along with how many of them are unread.
+func (cfg *apiConfig) handlerGetNotifications(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.getUserIDFromRequest(req)
+	if err != nil {
+		logger(req.Context()).Warn("Error authenticating user", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	params := database.ListNotificationsParams{UserID: userID, Limit: p.Limit + 1}
+	params.CursorCreatedAt, params.CursorID = cursorParams(p.Cursor)
+
+	rows, err := cfg.db.ListNotifications(req.Context(), params)
+	if err != nil {
+		logger(req.Context()).Error("Error getting notifications from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting notifications")
+		return
+	}
+
+	unreadCount, err := cfg.db.CountUnreadNotifications(req.Context(), userID)
+	if err != nil {
+		logger(req.Context()).Error("Error counting unread notifications", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting notifications")
+		return
+	}
+
+	notifications := Notifications{Entries: []Notification{}, UnreadCount: unreadCount}
+
+	rows, notifications.NextCursor = trimPage(rows, p.Limit, func(row database.ListNotificationsRow) cursor {
+		return cursor{CreatedAt: row.CreatedAt, ID: row.ID}
+	})
+
+	for _, row := range rows {
+		notifications.Entries = append(notifications.Entries, notificationFromDB(row))
+	}
+
+	respondWithJSON(w, http.StatusOK, notifications)
+}
+
+// handlerMarkNotificationsRead marks the listed notifications as read, or all
+// of the caller's notifications when the body is empty or has no IDs.
+func (cfg *apiConfig) handlerMarkNotificationsRead(w http.ResponseWriter, req *http.Request) {
+	userID, ok := cfg.getActiveUserIDFromRequest(w, req)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		logger(req.Context()).Warn("Error decoding notification IDs", "error", err)
+		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	params := database.MarkNotificationsReadParams{UserID: userID}
+	if len(body.IDs) > 0 {
+		params.Ids = body.IDs
+	}
+
+	if err := cfg.db.MarkNotificationsRead(req.Context(), params); err != nil {
+		logger(req.Context()).Error("Error marking notifications read", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Error updating notifications")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// notificationFromDB converts a notification row, which GetNotification
+// also produces, into its JSON representation.
+func notificationFromDB(row database.ListNotificationsRow) Notification {
+	notification := Notification{
+		ID:            row.ID,
+		Type:          row.Type,
+		ActorID:       row.ActorID,
+		ActorUsername: row.ActorUsername.String,
+		Read:          row.ReadAt.Valid,
+		CreatedAt:     row.CreatedAt,
+	}
+	if row.ChirpID.Valid {
+		notification.ChirpID = &row.ChirpID.UUID
+	}
+
+	return notification
+}
+
+// notify records a notification for userID about something actorID did. Users
+// are never notified about their own actions, and repeats of an earlier
+// notification are ignored. It is run with transactional queries alongside
+// the write that triggered it so the two succeed or fail together.
+func notify(
+	ctx context.Context,
+	qtx *database.Queries,
+	userID, actorID uuid.UUID,
+	notificationType string,
+	chirpID uuid.NullUUID,
+) error {
+	if userID == actorID {
+		return nil
+	}
+
+	params := database.CreateNotificationParams{
+		UserID:  userID,
+		ActorID: actorID,
+		Type:    notificationType,
+		ChirpID: chirpID,
+	}
+
+	return qtx.CreateNotification(ctx, params)
+}
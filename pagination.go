@@ -0,0 +1,172 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// cursor identifies a position in a list ordered by (created_at, id). It is
+// handed to clients as an opaque string and passed back via ?cursor=.
+type cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func encodeCursor(c cursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, errors.New("invalid cursor")
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return cursor{}, errors.New("invalid cursor")
+	}
+
+	c := cursor{}
+	if c.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return cursor{}, errors.New("invalid cursor")
+	}
+	if c.ID, err = uuid.Parse(id); err != nil {
+		return cursor{}, errors.New("invalid cursor")
+	}
+
+	return c, nil
+}
+
+// cursorParams converts c into the nullable keyset arguments taken by the
+// paginated queries. A nil cursor starts from the beginning of the list.
+func cursorParams(c *cursor) (sql.NullTime, uuid.NullUUID) {
+	if c == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+
+	return sql.NullTime{Time: c.CreatedAt, Valid: true}, uuid.NullUUID{UUID: c.ID, Valid: true}
+}
+
+// trimPage cuts rows, fetched with a limit of limit+1, down to one page. If the
+// extra row came back another page follows, and trimPage also returns the
+// cursor for it, taken from the last row kept.
+func trimPage[T any](rows []T, limit int32, key func(T) cursor) ([]T, string) {
+	return trimPageBy(rows, limit, key, encodeCursor)
+}
+
+// trimPageBy is like trimPage for lists ordered by something other than
+// (created_at, id), whose cursors have their own type and encoding.
+func trimPageBy[T, C any](rows []T, limit int32, key func(T) C, encode func(C) string) ([]T, string) {
+	if len(rows) <= int(limit) {
+		return rows, ""
+	}
+
+	rows = rows[:limit]
+	return rows, encode(key(rows[len(rows)-1]))
+}
+
+// page holds the pagination parameters common to every list endpoint.
+type page struct {
+	Limit  int32
+	Cursor *cursor
+	Desc   bool
+}
+
+// parsePage reads the limit and cursor of a list that has a fixed order.
+func parsePage(query url.Values) (page, error) {
+	if err := checkFixedOrder(query); err != nil {
+		return page{}, err
+	}
+
+	return parseLimitAndCursor(query)
+}
+
+// checkFixedOrder rejects ?sort= on a list that has a fixed order, rather
+// than silently ignoring it.
+func checkFixedOrder(query url.Values) error {
+	if query.Has("sort") {
+		return errors.New("sort is not supported")
+	}
+
+	return nil
+}
+
+// parseSortablePage is like parsePage for lists that can also be read
+// newest first, with ?sort=desc.
+func parseSortablePage(query url.Values) (page, error) {
+	p, err := parseLimitAndCursor(query)
+	if err != nil {
+		return page{}, err
+	}
+
+	switch strings.ToLower(query.Get("sort")) {
+	case "", "asc":
+	case "desc":
+		p.Desc = true
+	default:
+		return page{}, errors.New("invalid sort")
+	}
+
+	return p, nil
+}
+
+func parseLimitAndCursor(query url.Values) (page, error) {
+	limit, err := parseLimit(query)
+	if err != nil {
+		return page{}, err
+	}
+
+	p := page{Limit: limit}
+
+	if c := query.Get("cursor"); c != "" {
+		decoded, err := decodeCursor(c)
+		if err != nil {
+			return page{}, err
+		}
+		p.Cursor = &decoded
+	}
+
+	return p, nil
+}
+
+func parseLimit(query url.Values) (int32, error) {
+	limit := query.Get("limit")
+	if limit == "" {
+		return defaultPageLimit, nil
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 {
+		return 0, errors.New("invalid limit")
+	}
+
+	return int32(min(n, maxPageLimit)), nil
+}
+
+func parseAuthorID(query url.Values) (uuid.NullUUID, error) {
+	author := query.Get("author_id")
+	if author == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	authorID, err := uuid.Parse(author)
+	if err != nil {
+		return uuid.NullUUID{}, errors.New("invalid author ID")
+	}
+
+	return uuid.NullUUID{UUID: authorID, Valid: true}, nil
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name      string
+		createdAt time.Time
+	}{
+		{
+			name:      "Whole seconds",
+			createdAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name:      "Microseconds from Postgres",
+			createdAt: time.Date(2025, 1, 2, 3, 4, 5, 123456000, time.UTC),
+		},
+		{
+			name:      "Nanoseconds",
+			createdAt: time.Date(2025, 1, 2, 3, 4, 5, 1, time.UTC),
+		},
+		{
+			name:      "Non-UTC time zone",
+			createdAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("UTC+5", 5*60*60)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := cursor{CreatedAt: tt.createdAt, ID: id}
+
+			got, err := decodeCursor(encodeCursor(want))
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			if !got.CreatedAt.Equal(want.CreatedAt) {
+				t.Errorf("decodeCursor() CreatedAt = %v, want %v", got.CreatedAt, want.CreatedAt)
+			}
+			if got.ID != want.ID {
+				t.Errorf("decodeCursor() ID = %v, want %v", got.ID, want.ID)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	id := uuid.New()
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{
+			name:   "Empty",
+			cursor: "",
+		},
+		{
+			name:   "Not base64",
+			cursor: "not a cursor!",
+		},
+		{
+			name:   "Padded base64",
+			cursor: encode("2025-01-02T03:04:05Z|"+id.String()) + "==",
+		},
+		{
+			name:   "Missing separator",
+			cursor: encode("2025-01-02T03:04:05Z" + id.String()),
+		},
+		{
+			name:   "Missing time",
+			cursor: encode("|" + id.String()),
+		},
+		{
+			name:   "Missing ID",
+			cursor: encode("2025-01-02T03:04:05Z|"),
+		},
+		{
+			name:   "Tampered time",
+			cursor: encode("2025-13-02T03:04:05Z|" + id.String()),
+		},
+		{
+			name:   "Tampered ID",
+			cursor: encode("2025-01-02T03:04:05Z|" + id.String()[:35]),
+		},
+		{
+			name:   "Extra field",
+			cursor: encode("2025-01-02T03:04:05Z|" + id.String() + "|1"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor); err == nil {
+				t.Errorf("decodeCursor(%q) returned no error", tt.cursor)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   url.Values
+		want    int32
+		wantErr bool
+	}{
+		{
+			name:  "Default",
+			query: url.Values{},
+			want:  defaultPageLimit,
+		},
+		{
+			name:  "Minimum",
+			query: url.Values{"limit": {"1"}},
+			want:  1,
+		},
+		{
+			name:  "Within bounds",
+			query: url.Values{"limit": {"50"}},
+			want:  50,
+		},
+		{
+			name:  "Maximum",
+			query: url.Values{"limit": {"100"}},
+			want:  maxPageLimit,
+		},
+		{
+			name:  "Above maximum is clamped",
+			query: url.Values{"limit": {"101"}},
+			want:  maxPageLimit,
+		},
+		{
+			name:    "Zero",
+			query:   url.Values{"limit": {"0"}},
+			wantErr: true,
+		},
+		{
+			name:    "Negative",
+			query:   url.Values{"limit": {"-1"}},
+			wantErr: true,
+		},
+		{
+			name:    "Not a number",
+			query:   url.Values{"limit": {"ten"}},
+			wantErr: true,
+		},
+		{
+			name:    "Overflows int",
+			query:   url.Values{"limit": {"99999999999999999999"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLimit(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLimit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseLimit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	c := cursor{CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), ID: uuid.New()}
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		sortable   bool
+		wantLimit  int32
+		wantCursor bool
+		wantDesc   bool
+		wantErr    bool
+	}{
+		{
+			name:      "Defaults",
+			query:     url.Values{},
+			wantLimit: defaultPageLimit,
+		},
+		{
+			name:       "Limit and cursor",
+			query:      url.Values{"limit": {"5"}, "cursor": {encodeCursor(c)}},
+			wantLimit:  5,
+			wantCursor: true,
+		},
+		{
+			name:    "Invalid cursor",
+			query:   url.Values{"cursor": {"bogus"}},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid limit",
+			query:   url.Values{"limit": {"0"}},
+			wantErr: true,
+		},
+		{
+			name:    "Sort on a fixed-order list",
+			query:   url.Values{"sort": {"desc"}},
+			wantErr: true,
+		},
+		{
+			name:      "Ascending",
+			query:     url.Values{"sort": {"asc"}},
+			sortable:  true,
+			wantLimit: defaultPageLimit,
+		},
+		{
+			name:      "Descending",
+			query:     url.Values{"sort": {"DESC"}},
+			sortable:  true,
+			wantLimit: defaultPageLimit,
+			wantDesc:  true,
+		},
+		{
+			name:     "Unknown sort",
+			query:    url.Values{"sort": {"newest"}},
+			sortable: true,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parse := parsePage
+			if tt.sortable {
+				parse = parseSortablePage
+			}
+
+			got, err := parse(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Limit != tt.wantLimit {
+				t.Errorf("parse() Limit = %v, want %v", got.Limit, tt.wantLimit)
+			}
+			if (got.Cursor != nil) != tt.wantCursor {
+				t.Errorf("parse() Cursor = %v, wantCursor %v", got.Cursor, tt.wantCursor)
+			}
+			if got.Cursor != nil && (got.Cursor.ID != c.ID || !got.Cursor.CreatedAt.Equal(c.CreatedAt)) {
+				t.Errorf("parse() Cursor = %v, want %v", *got.Cursor, c)
+			}
+			if got.Desc != tt.wantDesc {
+				t.Errorf("parse() Desc = %v, want %v", got.Desc, tt.wantDesc)
+			}
+		})
+	}
+}
+
+func TestTrimPage(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := make([]cursor, 4)
+	for i := range rows {
+		rows[i] = cursor{CreatedAt: start.Add(time.Duration(i) * time.Second), ID: uuid.New()}
+	}
+	key := func(c cursor) cursor { return c }
+
+	tests := []struct {
+		name     string
+		rows     []cursor
+		limit    int32
+		wantRows int
+		wantNext bool
+	}{
+		{
+			name:     "Empty",
+			rows:     nil,
+			limit:    3,
+			wantRows: 0,
+		},
+		{
+			name:     "Fewer rows than the limit",
+			rows:     rows[:2],
+			limit:    3,
+			wantRows: 2,
+		},
+		{
+			name:     "Exactly the limit",
+			rows:     rows[:3],
+			limit:    3,
+			wantRows: 3,
+		},
+		{
+			name:     "One more than the limit",
+			rows:     rows[:4],
+			limit:    3,
+			wantRows: 3,
+			wantNext: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, next := trimPage(tt.rows, tt.limit, key)
+			if len(got) != tt.wantRows {
+				t.Errorf("trimPage() returned %d rows, want %d", len(got), tt.wantRows)
+			}
+			if (next != "") != tt.wantNext {
+				t.Fatalf("trimPage() next cursor = %q, wantNext %v", next, tt.wantNext)
+			}
+			if next == "" {
+				return
+			}
+
+			decoded, err := decodeCursor(next)
+			if err != nil {
+				t.Fatalf("decodeCursor() error = %v", err)
+			}
+			last := got[len(got)-1]
+			if decoded.ID != last.ID || !decoded.CreatedAt.Equal(last.CreatedAt) {
+				t.Errorf("trimPage() next cursor = %v, want the last row kept %v", decoded, last)
+			}
+		})
+	}
+}
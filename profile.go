@@ -0,0 +1,162 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/entities"
+)
+
+const (
+	minUsernameLength    = 3
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 2048
+)
+
+// reservedUsernames cannot be claimed because they would be confusing in
+// mentions or could be mistaken for official accounts.
+var reservedUsernames = []string{
+	"admin",
+	"administrator",
+	"api",
+	"chirpy",
+	"help",
+	"moderator",
+	"root",
+	"support",
+	"system",
+}
+
+// Profile is the public view of a user. It must never include the email
+// address or anything else that is private to the account.
+type Profile struct {
+	ID             uuid.UUID `json:"id"`
+	Username       string    `json:"username,omitempty"`
+	DisplayName    string    `json:"display_name"`
+	Bio            string    `json:"bio"`
+	AvatarURL      string    `json:"avatar_url"`
+	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	FollowerCount  int64     `json:"follower_count"`
+	FollowingCount int64     `json:"following_count"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// profileParams holds the validated profile fields from a request body. A
+// NULL field was not supplied and should be left unchanged.
+type profileParams struct {
+	Username    sql.NullString
+	DisplayName sql.NullString
+	Bio         sql.NullString
+	AvatarURL   sql.NullString
+}
+
+func (cfg *apiConfig) handlerGetUserProfile(w http.ResponseWriter, req *http.Request) {
+	dbUser, ok := cfg.getUserFromPath(w, req)
+	if !ok {
+		return
+	}
+
+	counts, err := cfg.db.GetUserFollowCounts(req.Context(), dbUser.ID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting follow counts from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting user")
+		return
+	}
+
+	profile := Profile{
+		ID:             dbUser.ID,
+		Username:       dbUser.Username.String,
+		DisplayName:    dbUser.DisplayName,
+		Bio:            dbUser.Bio,
+		AvatarURL:      dbUser.AvatarUrl,
+		IsChirpyRed:    dbUser.IsChirpyRed,
+		FollowerCount:  counts.FollowerCount,
+		FollowingCount: counts.FollowingCount,
+		CreatedAt:      dbUser.CreatedAt,
+	}
+
+	respondWithJSON(w, http.StatusOK, profile)
+}
+
+// parseProfile validates the profile fields of a user request body.
+func parseProfile(user User) (profileParams, error) {
+	params := profileParams{}
+
+	if user.Username != "" {
+		if err := validateUsername(user.Username); err != nil {
+			return profileParams{}, err
+		}
+		params.Username = sql.NullString{String: user.Username, Valid: true}
+	}
+
+	if user.DisplayName != nil {
+		displayName := strings.TrimSpace(*user.DisplayName)
+		if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+			return profileParams{}, errors.New("Display name is too long")
+		}
+		params.DisplayName = sql.NullString{String: displayName, Valid: true}
+	}
+
+	if user.Bio != nil {
+		bio := strings.TrimSpace(*user.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			return profileParams{}, errors.New("Bio is too long")
+		}
+		params.Bio = sql.NullString{String: bio, Valid: true}
+	}
+
+	if user.AvatarURL != nil {
+		if err := validateAvatarURL(*user.AvatarURL); err != nil {
+			return profileParams{}, err
+		}
+		params.AvatarURL = sql.NullString{String: *user.AvatarURL, Valid: true}
+	}
+
+	return params, nil
+}
+
+// validateUsername enforces the rules for handles: 3-15 letters, digits or
+// underscores, at least one letter, and not a reserved name. Handles are
+// unique regardless of case.
+func validateUsername(username string) error {
+	if !entities.IsUsername(username) || len(username) < minUsernameLength {
+		return errors.New("Username must be 3-15 letters, digits or underscores")
+	}
+
+	if strings.Trim(username, "0123456789_") == "" {
+		return errors.New("Username must contain a letter")
+	}
+
+	if slices.Contains(reservedUsernames, strings.ToLower(username)) {
+		return errors.New("Username is reserved")
+	}
+
+	return nil
+}
+
+// validateAvatarURL accepts an empty string, which clears the avatar, or an
+// absolute http(s) URL.
+func validateAvatarURL(avatarURL string) error {
+	if avatarURL == "" {
+		return nil
+	}
+
+	if len(avatarURL) > maxAvatarURLLength {
+		return errors.New("Avatar URL is too long")
+	}
+
+	u, err := url.Parse(avatarURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("Avatar URL must be an http or https URL")
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/database"
+)
+
+// QuotedChirp is the original Chirp embedded in a rechirp or quote. Once the
+// original has been deleted only its ID is kept and Unavailable is set.
+type QuotedChirp struct {
+	ID          uuid.UUID  `json:"id"`
+	Body        string     `json:"body,omitempty"`
+	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	Unavailable bool       `json:"unavailable"`
+}
+
+func (cfg *apiConfig) handlerRechirp(w http.ResponseWriter, req *http.Request) {
+	chirpID := req.PathValue("chirpID")
+	chirpUUID, err := uuid.Parse(chirpID)
+	if err != nil {
+		logger(req.Context()).Warn("Error parsing Chirp ID", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID")
+		return
+	}
+
+	userID, ok := cfg.getActiveUserIDFromRequest(w, req)
+	if !ok {
+		return
+	}
+
+	original, err := cfg.getRechirpableChirp(req.Context(), chirpUUID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting Chirp from DB", "error", err)
+
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusNotFound, "Chirp not found")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirp")
+		}
+		return
+	}
+
+	params := database.CreateRechirpParams{
+		UserID:      userID,
+		RechirpOfID: uuid.NullUUID{UUID: original.ID, Valid: true},
+	}
+	dbChirp, err := cfg.db.CreateRechirp(req.Context(), params)
+	if err != nil {
+		logger(req.Context()).Error("Error creating rechirp", "error", err)
+
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusConflict, "Chirp already rechirped")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Error creating rechirp")
+		}
+		return
+	}
+
+	chirps := []Chirp{chirpFromDB(dbChirp)}
+	if err := cfg.decorateChirps(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, chirps); err != nil {
+		logger(req.Context()).Error("Error decorating Chirps", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirp")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, chirps[0])
+}
+
+// getRechirpableChirp returns the Chirp that a rechirp or quote of chirpID
+// should point at. Rechirps resolve to their original so chains stay flat.
+// Deleted Chirps are reported as sql.ErrNoRows.
+func (cfg *apiConfig) getRechirpableChirp(ctx context.Context, chirpID uuid.UUID) (database.Chirp, error) {
+	dbChirp, err := cfg.db.GetChirp(ctx, chirpID)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	if dbChirp.RechirpOfID.Valid {
+		dbChirp, err = cfg.db.GetChirp(ctx, dbChirp.RechirpOfID.UUID)
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+
+	if dbChirp.DeletedAt.Valid {
+		return database.Chirp{}, sql.ErrNoRows
+	}
+
+	return dbChirp, nil
+}
+
+// embedReferencedChirps sets RechirpOf and QuoteOf on chirps, loading every
+// referenced original in one query.
+func (cfg *apiConfig) embedReferencedChirps(ctx context.Context, chirps []Chirp) error {
+	ids := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.RechirpOfID != nil {
+			ids = append(ids, *chirp.RechirpOfID)
+		}
+		if chirp.QuoteOfID != nil {
+			ids = append(ids, *chirp.QuoteOfID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	dbChirps, err := cfg.db.GetChirpsByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	originals := make(map[uuid.UUID]database.Chirp, len(dbChirps))
+	for _, dbChirp := range dbChirps {
+		originals[dbChirp.ID] = dbChirp
+	}
+
+	for i := range chirps {
+		if chirps[i].RechirpOfID != nil {
+			chirps[i].RechirpOf = newQuotedChirp(*chirps[i].RechirpOfID, originals)
+		}
+		if chirps[i].QuoteOfID != nil {
+			chirps[i].QuoteOf = newQuotedChirp(*chirps[i].QuoteOfID, originals)
+		}
+	}
+
+	return nil
+}
+
+func newQuotedChirp(id uuid.UUID, originals map[uuid.UUID]database.Chirp) *QuotedChirp {
+	original, ok := originals[id]
+	if !ok || original.DeletedAt.Valid {
+		return &QuotedChirp{ID: id, Unavailable: true}
+	}
+
+	return &QuotedChirp{
+		ID:        original.ID,
+		Body:      original.Body,
+		UserID:    &original.UserID,
+		CreatedAt: &original.CreatedAt,
+	}
+}
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/database"
+	"github.com/keithcrooks/chirpy/internal/moderation"
+)
+
+const maxReportReasonLength = 500
+
+// Report statuses. They match the CHECK constraint on reports.status.
+const (
+	reportOpen     = "open"
+	reportResolved = "resolved"
+)
+
+// Moderation actions. They match the CHECK constraint on
+// moderation_actions.action.
+const (
+	actionDismiss = "dismiss"
+	actionHide    = "hide"
+	actionSuspend = "suspend"
+)
+
+// Report is a complaint about a Chirp or a user. ReporterID is omitted for
+// reports raised automatically by a word list. UserID is the reported user,
+// or the author of the reported Chirp.
+type Report struct {
+	ID         uuid.UUID          `json:"id"`
+	ReporterID *uuid.UUID         `json:"reporter_id,omitempty"`
+	ChirpID    *uuid.UUID         `json:"chirp_id,omitempty"`
+	UserID     uuid.UUID          `json:"user_id"`
+	Reason     string             `json:"reason"`
+	Status     string             `json:"status"`
+	Actions    []ModerationAction `json:"actions"`
+	CreatedAt  time.Time          `json:"created_at"`
+	ResolvedAt *time.Time         `json:"resolved_at,omitempty"`
+}
+
+type Reports struct {
+	Entries    []Report `json:"reports"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// ModerationAction records what a moderator did about a report, and when.
+type ModerationAction struct {
+	ID          uuid.UUID  `json:"id"`
+	Action      string     `json:"action"`
+	ModeratorID uuid.UUID  `json:"moderator_id"`
+	ChirpID     *uuid.UUID `json:"chirp_id,omitempty"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Note        string     `json:"note,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// handlerCreateReport lets a user report a Chirp, or another user directly,
+// for review by a moderator.
+func (cfg *apiConfig) handlerCreateReport(w http.ResponseWriter, req *http.Request) {
+	reporterID, ok := cfg.getActiveUserIDFromRequest(w, req)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		ChirpID *uuid.UUID `json:"chirp_id"`
+		UserID  *uuid.UUID `json:"user_id"`
+		Reason  string     `json:"reason"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		logger(req.Context()).Warn("Error decoding report", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Could not read report")
+		return
+	}
+
+	if (body.ChirpID == nil) == (body.UserID == nil) {
+		respondWithError(w, http.StatusBadRequest, "Report must name either a Chirp or a user")
+		return
+	}
+
+	reason := strings.TrimSpace(body.Reason)
+	if reason == "" || len(reason) > maxReportReasonLength {
+		respondWithError(w, http.StatusBadRequest, "Reason must be 1-500 characters")
+		return
+	}
+
+	params := database.CreateReportParams{
+		ReporterID: uuid.NullUUID{UUID: reporterID, Valid: true},
+		Reason:     reason,
+	}
+
+	if body.ChirpID != nil {
+		dbChirp, err := cfg.db.GetChirp(req.Context(), *body.ChirpID)
+		if err != nil {
+			logger(req.Context()).Error("Error getting reported Chirp", "error", err)
+
+			switch err {
+			case sql.ErrNoRows:
+				respondWithError(w, http.StatusNotFound, "Chirp not found")
+			default:
+				respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirp")
+			}
+			return
+		}
+
+		if dbChirp.DeletedAt.Valid {
+			respondWithError(w, http.StatusNotFound, "Chirp not found")
+			return
+		}
+		params.ChirpID = uuid.NullUUID{UUID: dbChirp.ID, Valid: true}
+		params.UserID = dbChirp.UserID
+	} else {
+		dbUser, err := cfg.db.GetUser(req.Context(), *body.UserID)
+		if err != nil {
+			logger(req.Context()).Error("Error getting reported user", "error", err)
+
+			switch err {
+			case sql.ErrNoRows:
+				respondWithError(w, http.StatusNotFound, "User not found")
+			default:
+				respondWithError(w, http.StatusInternalServerError, "Unknown error getting user")
+			}
+			return
+		}
+		params.UserID = dbUser.ID
+	}
+
+	if params.UserID == reporterID {
+		respondWithError(w, http.StatusBadRequest, "You cannot report yourself")
+		return
+	}
+
+	dbReport, err := cfg.db.CreateReport(req.Context(), params)
+	if err != nil {
+		logger(req.Context()).Error("Error creating report", "error", err)
+
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusConflict, "You have already reported this")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Error creating report")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, reportFromDB(dbReport))
+}
+
+// handlerGetReports returns the moderation queue, oldest first. It lists open
+// reports unless ?status=resolved is given.
+func (cfg *apiConfig) handlerGetReports(w http.ResponseWriter, req *http.Request) {
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status := req.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = reportOpen
+	case reportOpen, reportResolved:
+	default:
+		respondWithError(w, http.StatusBadRequest, "invalid status")
+		return
+	}
+
+	params := database.ListReportsParams{Status: status, Limit: p.Limit + 1}
+	params.CursorCreatedAt, params.CursorID = cursorParams(p.Cursor)
+
+	dbReports, err := cfg.db.ListReports(req.Context(), params)
+	if err != nil {
+		logger(req.Context()).Error("Error getting reports from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting reports")
+		return
+	}
+
+	reports := Reports{Entries: []Report{}}
+
+	dbReports, reports.NextCursor = trimPage(dbReports, p.Limit, func(r database.Report) cursor {
+		return cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+	})
+
+	for _, dbReport := range dbReports {
+		reports.Entries = append(reports.Entries, reportFromDB(dbReport))
+	}
+
+	if err := cfg.loadModerationActions(req.Context(), reports.Entries); err != nil {
+		logger(req.Context()).Error("Error getting moderation actions from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting reports")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, reports)
+}
+
+func (cfg *apiConfig) handlerGetReport(w http.ResponseWriter, req *http.Request) {
+	dbReport, ok := cfg.getReportFromPath(w, req)
+	if !ok {
+		return
+	}
+
+	reports := []Report{reportFromDB(dbReport)}
+	if err := cfg.loadModerationActions(req.Context(), reports); err != nil {
+		logger(req.Context()).Error("Error getting moderation actions from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting report")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, reports[0])
+}
+
+// handlerModerateReport resolves an open report by dismissing it, hiding the
+// reported Chirp or suspending the reported user. The action is recorded
+// against the moderator making the request.
+func (cfg *apiConfig) handlerModerateReport(w http.ResponseWriter, req *http.Request) {
+	moderatorID, err := cfg.getUserIDFromRequest(req)
+	if err != nil {
+		logger(req.Context()).Warn("Error authenticating user", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	dbReport, ok := cfg.getReportFromPath(w, req)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Action string `json:"action"`
+		Note   string `json:"note"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		logger(req.Context()).Warn("Error decoding moderation action", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Could not read moderation action")
+		return
+	}
+
+	switch body.Action {
+	case actionDismiss, actionSuspend:
+	case actionHide:
+		if !dbReport.ChirpID.Valid {
+			respondWithError(w, http.StatusBadRequest, "Only reported Chirps can be hidden")
+			return
+		}
+	default:
+		respondWithError(w, http.StatusBadRequest, "Action must be dismiss, hide or suspend")
+		return
+	}
+
+	if dbReport.Status != reportOpen {
+		respondWithError(w, http.StatusConflict, "Report has already been resolved")
+		return
+	}
+
+	params := database.CreateModerationActionParams{
+		ModeratorID: moderatorID,
+		ReportID:    dbReport.ID,
+		Action:      body.Action,
+		ChirpID:     dbReport.ChirpID,
+		UserID:      dbReport.UserID,
+		Note:        strings.TrimSpace(body.Note),
+	}
+	dbReport, err = cfg.moderateReport(req.Context(), params)
+	if err != nil {
+		logger(req.Context()).Error("Error moderating report", "error", err)
+
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusConflict, "Report has already been resolved")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Error moderating report")
+		}
+		return
+	}
+
+	reports := []Report{reportFromDB(dbReport)}
+	if err := cfg.loadModerationActions(req.Context(), reports); err != nil {
+		logger(req.Context()).Error("Error getting moderation actions from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting report")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, reports[0])
+}
+
+// getReportFromPath looks up the report named by the {reportID} path value.
+// It writes an error response and returns false on failure.
+func (cfg *apiConfig) getReportFromPath(w http.ResponseWriter, req *http.Request) (database.Report, bool) {
+	reportID, err := uuid.Parse(req.PathValue("reportID"))
+	if err != nil {
+		logger(req.Context()).Warn("Error parsing report ID", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid report ID")
+		return database.Report{}, false
+	}
+
+	dbReport, err := cfg.db.GetReport(req.Context(), reportID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting report from DB", "error", err)
+
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusNotFound, "Report not found")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Unknown error getting report")
+		}
+		return database.Report{}, false
+	}
+
+	return dbReport, true
+}
+
+// moderateReport resolves a report, carries out the action and records it in
+// a single transaction. It returns sql.ErrNoRows if the report was resolved
+// concurrently. Suspending a user also revokes their refresh tokens, so they
+// are signed out once their current access token expires.
+func (cfg *apiConfig) moderateReport(
+	ctx context.Context,
+	params database.CreateModerationActionParams,
+) (database.Report, error) {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Report{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.withTx(tx)
+
+	dbReport, err := qtx.ResolveReport(ctx, params.ReportID)
+	if err != nil {
+		return database.Report{}, err
+	}
+
+	var attachmentIDs []uuid.UUID
+
+	switch params.Action {
+	case actionHide:
+		attachmentIDs, err = hideChirp(ctx, qtx, params.ChirpID.UUID)
+		if err != nil {
+			return database.Report{}, err
+		}
+	case actionSuspend:
+		if err := qtx.SuspendUser(ctx, params.UserID); err != nil {
+			return database.Report{}, err
+		}
+
+		if err := qtx.RevokeUserRefreshTokens(ctx, params.UserID); err != nil {
+			return database.Report{}, err
+		}
+	}
+
+	if _, err := qtx.CreateModerationAction(ctx, params); err != nil {
+		return database.Report{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.Report{}, err
+	}
+
+	cfg.deleteImages(ctx, attachmentIDs)
+	return dbReport, nil
+}
+
+// hideChirp takes down a reported Chirp, leaving a tombstone. Like a deleted
+// Chirp, it loses its hashtags, mentions and attachments, so it no longer
+// counts towards trending or serves its images. It returns the IDs of the
+// attachments, whose images are deleted once the transaction commits.
+func hideChirp(ctx context.Context, qtx *database.Queries, chirpID uuid.UUID) ([]uuid.UUID, error) {
+	attachments, err := qtx.GetChirpAttachments(ctx, []uuid.UUID{chirpID})
+	if err != nil {
+		return nil, err
+	}
+
+	attachmentIDs := make([]uuid.UUID, 0, len(attachments))
+	for _, attachment := range attachments {
+		attachmentIDs = append(attachmentIDs, attachment.ID)
+	}
+
+	if err := qtx.DeleteChirpHashtags(ctx, chirpID); err != nil {
+		return nil, err
+	}
+
+	if err := qtx.DeleteChirpMentions(ctx, chirpID); err != nil {
+		return nil, err
+	}
+
+	if err := qtx.DeleteChirpAttachments(ctx, chirpID); err != nil {
+		return nil, err
+	}
+
+	if err := qtx.HideChirp(ctx, chirpID); err != nil {
+		return nil, err
+	}
+
+	return attachmentIDs, nil
+}
+
+// loadModerationActions sets Actions on each of reports using a single query.
+func (cfg *apiConfig) loadModerationActions(ctx context.Context, reports []Report) error {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	reportIDs := make([]uuid.UUID, 0, len(reports))
+	for _, report := range reports {
+		reportIDs = append(reportIDs, report.ID)
+	}
+
+	dbActions, err := cfg.db.GetModerationActions(ctx, reportIDs)
+	if err != nil {
+		return err
+	}
+
+	actions := make(map[uuid.UUID][]ModerationAction, len(reports))
+	for _, dbAction := range dbActions {
+		action := ModerationAction{
+			ID:          dbAction.ID,
+			Action:      dbAction.Action,
+			ModeratorID: dbAction.ModeratorID,
+			UserID:      dbAction.UserID,
+			Note:        dbAction.Note,
+			CreatedAt:   dbAction.CreatedAt,
+		}
+		if dbAction.ChirpID.Valid {
+			action.ChirpID = &dbAction.ChirpID.UUID
+		}
+
+		actions[dbAction.ReportID] = append(actions[dbAction.ReportID], action)
+	}
+
+	for i := range reports {
+		if a, ok := actions[reports[i].ID]; ok {
+			reports[i].Actions = a
+		}
+	}
+
+	return nil
+}
+
+// reportChirpFlags raises a report, with no reporter, for a Chirp that
+// matched any word list with the flag action.
+func reportChirpFlags(ctx context.Context, qtx *database.Queries, dbChirp database.Chirp, matches []moderation.Match) error {
+	words := []string{}
+	for _, match := range matches {
+		if match.Action == moderation.Flag {
+			words = append(words, match.List+": "+match.Word)
+		}
+	}
+
+	if len(words) == 0 {
+		return nil
+	}
+
+	params := database.CreateReportParams{
+		ChirpID: uuid.NullUUID{UUID: dbChirp.ID, Valid: true},
+		UserID:  dbChirp.UserID,
+		Reason:  "Matched flagged words (" + strings.Join(words, ", ") + ")",
+	}
+
+	// An open report for the Chirp from an earlier edit is left as it is.
+	if _, err := qtx.CreateReport(ctx, params); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	return nil
+}
+
+func reportFromDB(dbReport database.Report) Report {
+	report := Report{
+		ID:        dbReport.ID,
+		UserID:    dbReport.UserID,
+		Reason:    dbReport.Reason,
+		Status:    dbReport.Status,
+		Actions:   []ModerationAction{},
+		CreatedAt: dbReport.CreatedAt,
+	}
+
+	if dbReport.ReporterID.Valid {
+		report.ReporterID = &dbReport.ReporterID.UUID
+	}
+
+	if dbReport.ChirpID.Valid {
+		report.ChirpID = &dbReport.ChirpID.UUID
+	}
+
+	if dbReport.ResolvedAt.Valid {
+		report.ResolvedAt = &dbReport.ResolvedAt.Time
+	}
+
+	return report
+}
@@ -1,17 +1,210 @@
 package main
 
-import "net/http"
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
 
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// platformDev is the PLATFORM value of a development deployment, the only
+// kind that may be reset.
+const platformDev = "dev"
+
+// resettableTables are the tables a reset may empty. Truncation cascades, so
+// emptying a table also empties every table that references it.
+var resettableTables = []string{
+	"users",
+	"chirps",
+	"chirp_attachments",
+	"chirp_flags",
+	"chirp_hashtags",
+	"chirp_likes",
+	"chirp_mentions",
+	"chirp_revisions",
+	"follows",
+	"hashtags",
+	"moderation_actions",
+	"notifications",
+	"refresh_tokens",
+	"reports",
+	"word_list_words",
+	"word_lists",
+}
+
+// defaultResetTables are emptied when a reset names no tables. They hold the
+// user data, but not the hashtags or the moderation word lists: migrations
+// insert the default word list, and neither a reset nor the fixtures would
+// put it back.
+var defaultResetTables = []string{
+	"users",
+	"chirps",
+	"chirp_attachments",
+	"chirp_flags",
+	"chirp_hashtags",
+	"chirp_likes",
+	"chirp_mentions",
+	"chirp_revisions",
+	"follows",
+	"moderation_actions",
+	"notifications",
+	"refresh_tokens",
+	"reports",
+}
+
+type ResetResult struct {
+	Truncated    []string `json:"truncated"`
+	SeededUsers  int      `json:"seeded_users"`
+	SeededChirps int      `json:"seeded_chirps"`
+}
+
+// handlerReset empties the database for development and testing. The body may
+// name the tables to empty, defaulting to defaultResetTables, and ask for the
+// fixtures file to be loaded afterwards:
+//
+//	{"tables": ["chirps", "follows"], "seed": true}
+//
+// Tables that reference the named ones are emptied too, and the response
+// lists every table that was. Everything happens in one transaction, so a
+// failure leaves the database as it was. The images of deleted attachments
+// are removed from the media store once it commits.
 func (cfg *apiConfig) handlerReset(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	cfg.fileserverHits.Store(0)
+	if cfg.platform != platformDev {
+		respondWithError(w, http.StatusForbidden, "Reset is only allowed in development")
+		return
+	}
 
-	if err := cfg.db.DeleteAllUsers(req.Context()); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Error deleting all users"))
+	var body struct {
+		Tables []string `json:"tables"`
+		Seed   bool     `json:"seed"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		logger(req.Context()).Warn("Error decoding reset request", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Could not read reset request")
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Counter reset to 0"))
+	tables := body.Tables
+	if len(tables) == 0 {
+		tables = defaultResetTables
+	}
+
+	for _, table := range tables {
+		if !slices.Contains(resettableTables, table) {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Table %q cannot be reset", table))
+			return
+		}
+	}
+
+	var fixtures fixtures
+	if body.Seed {
+		var err error
+		fixtures, err = loadFixtures(cfg.fixturesFile)
+		if err != nil {
+			logger(req.Context()).Error("Error loading fixtures", "error", err)
+			respondWithError(w, http.StatusInternalServerError, "Error loading fixtures")
+			return
+		}
+	}
+
+	result, err := cfg.reset(req.Context(), tables, fixtures)
+	if err != nil {
+		logger(req.Context()).Error("Error resetting database", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Error resetting database")
+		return
+	}
+
+	cfg.fileserverHits.Store(0)
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// reset truncates tables and then loads fixtures in a single transaction.
+// The table names must come from resettableTables.
+func (cfg *apiConfig) reset(ctx context.Context, tables []string, fixtures fixtures) (ResetResult, error) {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return ResetResult{}, err
+	}
+	defer tx.Rollback()
+
+	truncated, err := cascadedTables(ctx, tx, tables)
+	if err != nil {
+		return ResetResult{}, err
+	}
+
+	var attachmentIDs []uuid.UUID
+	if slices.Contains(truncated, "chirp_attachments") {
+		attachmentIDs, err = cfg.withTx(tx).ListAttachmentIDs(ctx)
+		if err != nil {
+			return ResetResult{}, err
+		}
+	}
+
+	quoted := make([]string, 0, len(tables))
+	for _, table := range tables {
+		quoted = append(quoted, pq.QuoteIdentifier(table))
+	}
+
+	if _, err := tx.ExecContext(ctx, "TRUNCATE "+strings.Join(quoted, ", ")+" CASCADE"); err != nil {
+		return ResetResult{}, err
+	}
+
+	result := ResetResult{Truncated: truncated}
+
+	result.SeededUsers, result.SeededChirps, err = seedFixtures(ctx, cfg.withTx(tx), fixtures)
+	if err != nil {
+		return ResetResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ResetResult{}, err
+	}
+
+	cfg.deleteImages(ctx, attachmentIDs)
+	return result, nil
+}
+
+// cascadedTables returns the tables that truncating tables with CASCADE
+// empties: the tables themselves and, transitively, every table with a
+// foreign key referencing one of them.
+func cascadedTables(ctx context.Context, tx *sql.Tx, tables []string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		WITH RECURSIVE cascaded(oid) AS (
+			SELECT unnest($1::text[]::regclass[])::oid
+			UNION
+			SELECT pg_constraint.conrelid
+			FROM pg_constraint
+			JOIN cascaded ON pg_constraint.confrelid = cascaded.oid
+			WHERE pg_constraint.contype = 'f'
+		)
+		SELECT pg_class.relname
+		FROM pg_class
+		JOIN cascaded ON pg_class.oid = cascaded.oid
+		ORDER BY pg_class.relname`,
+		pq.Array(tables),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var truncated []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		truncated = append(truncated, table)
+	}
+
+	return truncated, rows.Err()
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ChirpRevision struct {
+	ID        uuid.UUID `json:"id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (cfg *apiConfig) handlerGetChirpRevisions(w http.ResponseWriter, req *http.Request) {
+	chirpID := req.PathValue("chirpID")
+	chirpUUID, err := uuid.Parse(chirpID)
+	if err != nil {
+		logger(req.Context()).Warn("Error parsing Chirp ID", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID")
+		return
+	}
+
+	dbChirp, err := cfg.db.GetChirp(req.Context(), chirpUUID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting Chirp from DB", "error", err)
+
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusNotFound, "Chirp not found")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirp")
+		}
+		return
+	}
+
+	if dbChirp.DeletedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	dbRevisions, err := cfg.db.GetChirpRevisions(req.Context(), chirpUUID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting Chirp revisions from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirp revisions")
+		return
+	}
+
+	revisions := []ChirpRevision{}
+
+	for _, dbRevision := range dbRevisions {
+		revision := ChirpRevision{
+			ID:        dbRevision.ID,
+			ChirpID:   dbRevision.ChirpID,
+			Body:      dbRevision.Body,
+			CreatedAt: dbRevision.CreatedAt,
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	respondWithJSON(w, http.StatusOK, revisions)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/keithcrooks/chirpy/internal/auth"
+	"github.com/keithcrooks/chirpy/internal/database"
+)
+
+// Roles. They match the CHECK constraint on users.role.
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+// roleRanks orders the roles from least to most privileged. Each role may do
+// everything the roles below it can.
+var roleRanks = map[string]int{
+	roleUser:      0,
+	roleModerator: 1,
+	roleAdmin:     2,
+}
+
+// middlewareRequireRole only passes on requests whose access token was issued
+// to a user with at least the given role who has not been suspended. The role
+// is read from the token, so a change of role takes effect when the user next
+// logs in or refreshes.
+func (cfg *apiConfig) middlewareRequireRole(role string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, err := auth.GetBearerToken(req.Header)
+		if err != nil {
+			logger(req.Context()).Warn("Error getting bearer token", "error", err)
+			respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+			return
+		}
+
+		userID, tokenRole, err := auth.ValidateJWTWithRole(token, cfg.tokenSecret)
+		if err != nil {
+			logger(req.Context()).Warn("Error validating JWT", "error", err)
+			respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+			return
+		}
+		setRequestUser(req.Context(), userID)
+
+		// Tokens issued before roles existed carry none and rank as a user.
+		if roleRanks[tokenRole] < roleRanks[role] {
+			respondWithError(w, http.StatusForbidden, http.StatusText(http.StatusForbidden))
+			return
+		}
+
+		if !cfg.requireActiveUser(w, req, userID) {
+			return
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// handlerUpdateUserRole lets an admin grant or revoke the moderator and admin
+// roles.
+func (cfg *apiConfig) handlerUpdateUserRole(w http.ResponseWriter, req *http.Request) {
+	adminID, err := cfg.getUserIDFromRequest(req)
+	if err != nil {
+		logger(req.Context()).Warn("Error authenticating user", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	dbUser, ok := cfg.getUserFromPath(w, req)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		logger(req.Context()).Warn("Error decoding role", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Could not read role")
+		return
+	}
+
+	if _, ok := roleRanks[body.Role]; !ok {
+		respondWithError(w, http.StatusBadRequest, "Role must be user, moderator or admin")
+		return
+	}
+
+	// Stops the last admin from locking everyone out.
+	if dbUser.ID == adminID {
+		respondWithError(w, http.StatusBadRequest, "You cannot change your own role")
+		return
+	}
+
+	params := database.UpdateUserRoleParams{Role: body.Role, ID: dbUser.ID}
+	dbUser, err = cfg.db.UpdateUserRole(req.Context(), params)
+	if err != nil {
+		logger(req.Context()).Error("Error updating user role", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Error updating role")
+		return
+	}
+
+	user := User{
+		ID:          dbUser.ID,
+		CreatedAt:   dbUser.CreatedAt,
+		UpdatedAt:   dbUser.UpdatedAt,
+		Email:       dbUser.Email,
+		Role:        dbUser.Role,
+		IsChirpyRed: dbUser.IsChirpyRed,
+	}
+	setUserProfile(&user, dbUser)
+
+	respondWithJSON(w, http.StatusOK, user)
+}
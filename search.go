@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/database"
+)
+
+// SearchResult is a Chirp matching a search. Headline is an HTML excerpt of
+// the body with the matching words wrapped in <mark> tags. The body is
+// escaped before it is highlighted, so those are the only tags in it.
+type SearchResult struct {
+	Chirp
+	Rank     float32 `json:"rank"`
+	Headline string  `json:"headline"`
+}
+
+type SearchResults struct {
+	Entries    []SearchResult `json:"chirps"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// searchCursor identifies a position in a result list ordered by (rank, id).
+type searchCursor struct {
+	Rank float32
+	ID   uuid.UUID
+}
+
+func (cfg *apiConfig) handlerSearchChirps(w http.ResponseWriter, req *http.Request) {
+	viewerID, err := cfg.getOptionalUserIDFromRequest(req)
+	if err != nil {
+		logger(req.Context()).Warn("Error authenticating user", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	query := req.URL.Query()
+
+	q := strings.TrimSpace(query.Get("q"))
+	if q == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing search query")
+		return
+	}
+
+	if err := checkFixedOrder(query); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, err := parseLimit(query)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	authorID, err := parseAuthorID(query)
+	if err != nil {
+		logger(req.Context()).Warn("Error parsing author ID", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid author ID")
+		return
+	}
+
+	params := database.SearchChirpsParams{Query: q, AuthorID: authorID, Limit: limit + 1}
+	if c := query.Get("cursor"); c != "" {
+		after, err := decodeSearchCursor(c)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		params.CursorRank = sql.NullFloat64{Float64: float64(after.Rank), Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: after.ID, Valid: true}
+	}
+
+	rows, err := cfg.db.SearchChirps(req.Context(), params)
+	if err != nil {
+		logger(req.Context()).Error("Error searching Chirps", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error searching Chirps")
+		return
+	}
+
+	results := SearchResults{Entries: []SearchResult{}}
+
+	rows, results.NextCursor = trimPageBy(rows, limit, func(row database.SearchChirpsRow) searchCursor {
+		return searchCursor{Rank: row.Rank, ID: row.ID}
+	}, encodeSearchCursor)
+
+	chirps := make([]Chirp, 0, len(rows))
+	for _, row := range rows {
+		chirps = append(chirps, chirpFromDB(database.Chirp{
+			ID:        row.ID,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Body:      row.Body,
+			UserID:    row.UserID,
+			ReplyToID: row.ReplyToID,
+			QuoteOfID: row.QuoteOfID,
+			LikeCount: row.LikeCount,
+		}))
+	}
+
+	if err := cfg.decorateChirps(req.Context(), viewerID, chirps); err != nil {
+		logger(req.Context()).Error("Error decorating Chirps", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error searching Chirps")
+		return
+	}
+
+	for i, row := range rows {
+		result := SearchResult{Chirp: chirps[i], Rank: row.Rank, Headline: row.Headline}
+		results.Entries = append(results.Entries, result)
+	}
+
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+func encodeSearchCursor(c searchCursor) string {
+	raw := strconv.FormatFloat(float64(c.Rank), 'g', -1, 32) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeSearchCursor(s string) (searchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return searchCursor{}, errors.New("invalid cursor")
+	}
+
+	rank, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return searchCursor{}, errors.New("invalid cursor")
+	}
+
+	r, err := strconv.ParseFloat(rank, 32)
+	if err != nil {
+		return searchCursor{}, errors.New("invalid cursor")
+	}
+
+	c := searchCursor{Rank: float32(r)}
+	if c.ID, err = uuid.Parse(id); err != nil {
+		return searchCursor{}, errors.New("invalid cursor")
+	}
+
+	return c, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSearchCursorRoundTrip(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name string
+		rank float32
+	}{
+		{
+			name: "Zero rank",
+			rank: 0,
+		},
+		{
+			name: "Typical rank",
+			rank: 0.0607927,
+		},
+		{
+			name: "Tiny rank",
+			rank: 1e-20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := searchCursor{Rank: tt.rank, ID: id}
+
+			got, err := decodeSearchCursor(encodeSearchCursor(want))
+			if err != nil {
+				t.Fatalf("decodeSearchCursor() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("decodeSearchCursor() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeSearchCursorInvalid(t *testing.T) {
+	id := uuid.New()
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{
+			name:   "Not base64",
+			cursor: "not a cursor!",
+		},
+		{
+			name:   "Missing separator",
+			cursor: encode("0.5" + id.String()),
+		},
+		{
+			name:   "Tampered rank",
+			cursor: encode("high|" + id.String()),
+		},
+		{
+			name:   "Tampered ID",
+			cursor: encode("0.5|" + id.String()[:35]),
+		},
+		{
+			name:   "Time cursor",
+			cursor: encodeCursor(cursor{ID: id}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeSearchCursor(tt.cursor); err == nil {
+				t.Errorf("decodeSearchCursor(%q) returned no error", tt.cursor)
+			}
+		})
+	}
+}
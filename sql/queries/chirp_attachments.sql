@@ -0,0 +1,17 @@
+-- name: CreateChirpAttachment :exec
+INSERT INTO chirp_attachments (id, created_at, chirp_id, position, content_type, width, height)
+VALUES ($1, NOW(), $2, $3, $4, $5, $6);
+
+-- name: DeleteChirpAttachments :exec
+DELETE FROM chirp_attachments WHERE chirp_id = $1;
+
+-- name: ListAttachmentIDs :many
+SELECT id FROM chirp_attachments;
+
+-- name: GetChirpAttachment :one
+SELECT * FROM chirp_attachments WHERE id = $1;
+
+-- name: GetChirpAttachments :many
+SELECT * FROM chirp_attachments
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, position;
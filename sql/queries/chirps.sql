@@ -4,25 +4,154 @@ INSERT INTO chirps (
     created_at,
     updated_at,
     body,
-    user_id
+    user_id,
+    reply_to_id,
+    quote_of_id
 )
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
 RETURNING *;
 
--- name: GetAllChirps :many
-SELECT * FROM chirps ORDER BY created_at ASC;
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of_id)
+VALUES (gen_random_uuid(), NOW(), NOW(), '', $1, $2)
+ON CONFLICT (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL DO NOTHING
+RETURNING *;
+
+-- name: ListChirpsAsc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
 
--- name: GetAllChirpsByAuthor :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at ASC;
+-- name: ListChirpsDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
 
 -- name: GetChirp :one
 SELECT * FROM chirps WHERE id = $1;
 
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: UpdateChirpBody :one
+UPDATE chirps SET body = $1, updated_at = NOW() WHERE id = $2
+RETURNING *;
+
+-- name: AdjustChirpLikeCount :exec
+UPDATE chirps SET like_count = like_count + sqlc.arg('delta')::integer WHERE id = sqlc.arg('id')::uuid;
+
+-- name: LockChirp :exec
+SELECT id FROM chirps WHERE id = $1 FOR UPDATE;
+
 -- name: DeleteChirp :exec
 DELETE FROM chirps WHERE id = $1;
+
+-- name: TombstoneChirp :exec
+UPDATE chirps SET body = '', deleted_at = NOW(), updated_at = NOW() WHERE id = $1;
+
+-- name: HideChirp :exec
+UPDATE chirps SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1;
+
+-- name: ChirpHasDependents :one
+SELECT EXISTS (
+    SELECT 1 FROM chirps
+    WHERE reply_to_id = sqlc.arg('chirp_id')::uuid OR quote_of_id = sqlc.arg('chirp_id')::uuid
+);
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id, parent.created_at, parent.updated_at, parent.body, parent.user_id,
+        parent.reply_to_id, parent.quote_of_id, parent.like_count, parent.deleted_at, 1 AS depth
+    FROM chirps parent
+    JOIN chirps child ON child.reply_to_id = parent.id
+    WHERE child.id = $1
+    UNION ALL
+    SELECT parent.id, parent.created_at, parent.updated_at, parent.body, parent.user_id,
+        parent.reply_to_id, parent.quote_of_id, parent.like_count, parent.deleted_at, ancestors.depth + 1
+    FROM chirps parent
+    JOIN ancestors ON ancestors.reply_to_id = parent.id
+)
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, quote_of_id, like_count, deleted_at, depth
+FROM ancestors
+ORDER BY depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id,
+        chirps.reply_to_id, chirps.quote_of_id, chirps.like_count, chirps.deleted_at, 1 AS depth
+    FROM chirps
+    WHERE chirps.reply_to_id = sqlc.arg('chirp_id')::uuid
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id,
+        chirps.reply_to_id, chirps.quote_of_id, chirps.like_count, chirps.deleted_at, descendants.depth + 1
+    FROM chirps
+    JOIN descendants ON chirps.reply_to_id = descendants.id
+)
+SELECT id, created_at, updated_at, body, user_id, reply_to_id, quote_of_id, like_count, deleted_at, depth
+FROM descendants
+WHERE sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchChirps :many
+SELECT
+    chirps.id,
+    chirps.created_at,
+    chirps.updated_at,
+    chirps.body,
+    chirps.user_id,
+    chirps.reply_to_id,
+    chirps.quote_of_id,
+    chirps.like_count,
+    ts_rank(chirps.search_vector, query)::real AS rank,
+    ts_headline(
+        'english',
+        replace(replace(replace(replace(replace(chirps.body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;'),
+        query,
+        'StartSel=<mark>, StopSel=</mark>'
+    )::text AS headline
+FROM chirps, websearch_to_tsquery('english', sqlc.arg('query')::text) AS query
+WHERE chirps.search_vector @@ query
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+  AND (
+    sqlc.narg('cursor_rank')::real IS NULL
+    OR (ts_rank(chirps.search_vector, query)::real, chirps.id) < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY rank DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListTimelineChirps :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+  AND (
+    user_id = sqlc.arg('user_id')::uuid
+    OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = sqlc.arg('user_id')::uuid)
+  )
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
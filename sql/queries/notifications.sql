@@ -0,0 +1,31 @@
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: CreateNotification :exec
+INSERT INTO notifications (id, created_at, user_id, actor_id, type, chirp_id)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4)
+ON CONFLICT DO NOTHING;
+
+-- name: GetNotification :one
+SELECT notifications.*, users.username AS actor_username
+FROM notifications
+JOIN users ON users.id = notifications.actor_id
+WHERE notifications.id = $1;
+
+-- name: ListNotifications :many
+SELECT notifications.*, users.username AS actor_username
+FROM notifications
+JOIN users ON users.id = notifications.actor_id
+WHERE notifications.user_id = sqlc.arg('user_id')::uuid
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (notifications.created_at, notifications.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications SET read_at = NOW()
+WHERE user_id = sqlc.arg('user_id')::uuid
+  AND read_at IS NULL
+  AND (sqlc.narg('ids')::uuid[] IS NULL OR id = ANY(sqlc.narg('ids')::uuid[]));
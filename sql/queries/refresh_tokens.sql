@@ -8,3 +8,7 @@ SELECT * FROM refresh_tokens WHERE token = $1;
 
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens SET expires_at = NOW(), updated_at = NOW() WHERE token = $1;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens SET expires_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND expires_at > NOW();
@@ -0,0 +1,33 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, reporter_id, chirp_id, user_id, reason)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4)
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports WHERE id = $1;
+
+-- name: ListReports :many
+SELECT * FROM reports
+WHERE status = sqlc.arg('status')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ResolveReport :one
+UPDATE reports SET status = 'resolved', resolved_at = NOW()
+WHERE id = $1 AND status = 'open'
+RETURNING *;
+
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, created_at, moderator_id, report_id, action, chirp_id, user_id, note)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetModerationActions :many
+SELECT * FROM moderation_actions
+WHERE report_id = ANY(sqlc.arg('report_ids')::uuid[])
+ORDER BY created_at ASC, id ASC;
@@ -1,17 +1,56 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+INSERT INTO users (
+    id,
+    created_at,
+    updated_at,
+    email,
+    hashed_password,
+    username,
+    display_name,
+    bio,
+    avatar_url
+)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6)
 RETURNING *;
 
--- name: DeleteAllUsers :exec
-DELETE FROM users;
+-- name: GetUser :one
+SELECT * FROM users WHERE id = $1;
 
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
 
+-- name: GetUserByUsername :one
+SELECT * FROM users WHERE LOWER(username) = LOWER(sqlc.arg('username')::text);
+
+-- name: GetUsersByUsernames :many
+SELECT id, username FROM users WHERE LOWER(username) = ANY(sqlc.arg('usernames')::text[]);
+
+-- name: GetUserFollowCounts :one
+SELECT
+    (SELECT COUNT(*) FROM follows WHERE followee_id = sqlc.arg('user_id')::uuid) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follower_id = sqlc.arg('user_id')::uuid) AS following_count;
+
 -- name: UpdateUserEmailAndPassword :one
 UPDATE users SET email = $1, hashed_password = $2, updated_at = NOW() WHERE id = $3
 RETURNING id, created_at, updated_at, email, is_chirpy_red;
 
+-- name: UpdateUserProfile :one
+UPDATE users
+SET
+    username = COALESCE(sqlc.narg('username'), username),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    avatar_url = COALESCE(sqlc.narg('avatar_url'), avatar_url),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
 -- name: UpgradeUserToChirpyRed :exec
 UPDATE users SET is_chirpy_red = true WHERE id = $1;
+
+-- name: SuspendUser :exec
+UPDATE users SET suspended_at = NOW(), updated_at = NOW() WHERE id = $1 AND suspended_at IS NULL;
+
+-- name: UpdateUserRole :one
+UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2
+RETURNING *;
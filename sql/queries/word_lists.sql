@@ -0,0 +1,41 @@
+-- name: CreateChirpFlag :exec
+INSERT INTO chirp_flags (chirp_id, list_name, word, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: CreateWordList :one
+INSERT INTO word_lists (id, created_at, updated_at, name, action)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+RETURNING *;
+
+-- name: CreateWordListWord :exec
+INSERT INTO word_list_words (word_list_id, word)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteWordList :execrows
+DELETE FROM word_lists WHERE id = $1;
+
+-- name: DeleteWordListWords :exec
+DELETE FROM word_list_words WHERE word_list_id = $1;
+
+-- name: GetWordList :one
+SELECT * FROM word_lists WHERE id = $1;
+
+-- name: GetWordListWords :many
+SELECT word_list_id, word FROM word_list_words
+WHERE word_list_id = ANY(sqlc.arg('word_list_ids')::uuid[])
+ORDER BY word_list_id, word;
+
+-- name: ListModerationWords :many
+SELECT word_lists.name, word_lists.action, word_list_words.word
+FROM word_lists
+JOIN word_list_words ON word_list_words.word_list_id = word_lists.id
+ORDER BY word_lists.name, word_list_words.word;
+
+-- name: ListWordLists :many
+SELECT * FROM word_lists ORDER BY name;
+
+-- name: UpdateWordList :one
+UPDATE word_lists SET name = $1, action = $2, updated_at = NOW() WHERE id = $3
+RETURNING *;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN IF NOT EXISTS reply_to_id UUID REFERENCES chirps (id) ON DELETE SET NULL,
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS chirps_reply_to_id_idx ON chirps (reply_to_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_reply_to_id_idx;
+
+ALTER TABLE chirps
+DROP COLUMN IF EXISTS deleted_at,
+DROP COLUMN IF EXISTS reply_to_id;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN IF NOT EXISTS rechirp_of_id UUID REFERENCES chirps (id) ON DELETE CASCADE,
+ADD COLUMN IF NOT EXISTS quote_of_id UUID REFERENCES chirps (id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS chirps_user_id_rechirp_of_id_idx
+ON chirps (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS chirps_quote_of_id_idx ON chirps (quote_of_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_quote_of_id_idx;
+DROP INDEX IF EXISTS chirps_user_id_rechirp_of_id_idx;
+
+ALTER TABLE chirps
+DROP COLUMN IF EXISTS quote_of_id,
+DROP COLUMN IF EXISTS rechirp_of_id;
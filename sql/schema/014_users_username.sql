@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_idx ON users (LOWER(username));
+
+-- +goose Down
+DROP INDEX IF EXISTS users_username_lower_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS username;
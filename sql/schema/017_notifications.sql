@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('follow', 'like', 'reply', 'mention')),
+    chirp_id UUID,
+    read_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, created_at, id);
+
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+
+-- Repeating an action (unfollow then follow, editing a Chirp that mentions
+-- someone) must not notify the same user twice.
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_follow_idx ON notifications (user_id, actor_id)
+WHERE type = 'follow';
+
+CREATE UNIQUE INDEX IF NOT EXISTS notifications_chirp_idx ON notifications (user_id, actor_id, type, chirp_id)
+WHERE type IN ('like', 'reply', 'mention');
+
+-- +goose Down
+DROP TABLE IF EXISTS notifications;
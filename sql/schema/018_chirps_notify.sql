@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_chirp_created() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('chirp_created', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE OR REPLACE TRIGGER chirps_notify_created
+AFTER INSERT ON chirps
+FOR EACH ROW EXECUTE FUNCTION notify_chirp_created();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS chirps_notify_created ON chirps;
+
+DROP FUNCTION IF EXISTS notify_chirp_created();
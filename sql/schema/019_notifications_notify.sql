@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_notification_created() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('notification_created', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE OR REPLACE TRIGGER notifications_notify_created
+AFTER INSERT ON notifications
+FOR EACH ROW EXECUTE FUNCTION notify_notification_created();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS notifications_notify_created ON notifications;
+
+DROP FUNCTION IF EXISTS notify_notification_created();
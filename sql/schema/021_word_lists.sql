@@ -0,0 +1,42 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS word_lists (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL UNIQUE,
+    action TEXT NOT NULL CHECK (action IN ('mask', 'reject', 'flag'))
+);
+
+CREATE TABLE IF NOT EXISTS word_list_words (
+    word_list_id UUID NOT NULL,
+    word TEXT NOT NULL,
+    PRIMARY KEY (word_list_id, word),
+    FOREIGN KEY (word_list_id) REFERENCES word_lists (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS chirp_flags (
+    chirp_id UUID NOT NULL,
+    list_name TEXT NOT NULL,
+    word TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, list_name, word),
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE
+);
+
+-- The words that used to be hardcoded in filterChirp.
+INSERT INTO word_lists (id, created_at, updated_at, name, action)
+VALUES (gen_random_uuid(), NOW(), NOW(), 'default', 'mask')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO word_list_words (word_list_id, word)
+SELECT word_lists.id, words.word
+FROM word_lists, UNNEST(ARRAY['kerfuffle', 'sharbert', 'fornax']) AS words (word)
+WHERE word_lists.name = 'default'
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_flags;
+
+DROP TABLE IF EXISTS word_list_words;
+
+DROP TABLE IF EXISTS word_lists;
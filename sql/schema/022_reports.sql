@@ -0,0 +1,52 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP;
+
+-- A report is raised by a user against a Chirp or another user. Reports with
+-- no reporter were raised by a word list with the flag action.
+CREATE TABLE IF NOT EXISTS reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    reporter_id UUID,
+    chirp_id UUID,
+    user_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (reporter_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS reports_status_idx ON reports (status, created_at, id);
+
+-- A reporter may only have one open report against the same Chirp or user.
+CREATE UNIQUE INDEX IF NOT EXISTS reports_open_chirp_idx
+ON reports (COALESCE(reporter_id, '00000000-0000-0000-0000-000000000000'), chirp_id)
+WHERE status = 'open' AND chirp_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS reports_open_user_idx ON reports (reporter_id, user_id)
+WHERE status = 'open' AND chirp_id IS NULL;
+
+-- Moderation actions are an audit log, so they deliberately have no foreign
+-- keys: the record must outlive the report, Chirp and accounts involved.
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    moderator_id UUID NOT NULL,
+    report_id UUID NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('dismiss', 'hide', 'suspend')),
+    chirp_id UUID,
+    user_id UUID NOT NULL,
+    note TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS moderation_actions_report_id_idx ON moderation_actions (report_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS moderation_actions;
+
+DROP TABLE IF EXISTS reports;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS suspended_at;
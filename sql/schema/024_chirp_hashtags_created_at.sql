@@ -0,0 +1,10 @@
+-- +goose Up
+-- Edits used to re-date a Chirp's hashtags, pushing them back into trending.
+UPDATE chirp_hashtags
+SET created_at = chirps.created_at
+FROM chirps
+WHERE chirps.id = chirp_hashtags.chirp_id
+  AND chirp_hashtags.created_at <> chirps.created_at;
+
+-- +goose Down
+-- The edit times are not kept, so there is nothing to restore.
@@ -1,9 +1,98 @@
 package main
 
-import "net/http"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
 
+// readinessTimeout bounds all of the checks made by a readiness probe.
+const readinessTimeout = 2 * time.Second
+
+const (
+	checkOK      = "ok"
+	checkFailing = "failing"
+)
+
+type Readiness struct {
+	Status string                    `json:"status"`
+	Checks map[string]ReadinessCheck `json:"checks"`
+}
+
+type ReadinessCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// handlerStatus is the liveness check. It touches no dependencies, so it only
+// fails if the process cannot serve requests at all.
 func handlerStatus(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(http.StatusText(http.StatusOK)))
 }
+
+// handlerReadiness reports whether this instance should receive traffic: the
+// database answers, its schema is at least the version this build expects,
+// and the server is not shutting down. It responds 503 if any check fails.
+func (cfg *apiConfig) handlerReadiness(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithTimeout(req.Context(), readinessTimeout)
+	defer cancel()
+
+	readiness := Readiness{Status: checkOK, Checks: map[string]ReadinessCheck{}}
+	check := func(name string, err error) {
+		if err != nil {
+			readiness.Status = checkFailing
+			readiness.Checks[name] = ReadinessCheck{Status: checkFailing, Error: err.Error()}
+			return
+		}
+		readiness.Checks[name] = ReadinessCheck{Status: checkOK}
+	}
+
+	var shutdownErr error
+	if cfg.shuttingDown.Load() {
+		shutdownErr = errors.New("server is shutting down")
+	}
+	check("shutdown", shutdownErr)
+
+	dbErr := cfg.sqlDB.PingContext(ctx)
+	if dbErr != nil {
+		logger(ctx).Error("Error pinging database", "error", dbErr)
+		dbErr = errors.New("database is unreachable")
+	}
+	check("database", dbErr)
+
+	var migrationErr error
+	if dbErr == nil {
+		migrationErr = cfg.checkSchemaVersion(ctx)
+	} else {
+		migrationErr = errors.New("database is unreachable")
+	}
+	check("migrations", migrationErr)
+
+	code := http.StatusOK
+	if readiness.Status != checkOK {
+		code = http.StatusServiceUnavailable
+	}
+	respondWithJSON(w, code, readiness)
+}
+
+// checkSchemaVersion fails unless the database has every migration this build
+// was compiled with. A newer schema is fine: during a rolling deploy the first
+// new instance migrates while old ones are still serving, and migrations are
+// written to be compatible with the previous release.
+func (cfg *apiConfig) checkSchemaVersion(ctx context.Context) error {
+	applied, expected, err := cfg.migrator.GetVersions(ctx)
+	if err != nil {
+		logger(ctx).Error("Error getting schema version", "error", err)
+		return errors.New("could not read schema version")
+	}
+
+	if applied < expected {
+		return fmt.Errorf("schema is at version %d, want at least %d", applied, expected)
+	}
+
+	return nil
+}
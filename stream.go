@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/database"
+	"github.com/keithcrooks/chirpy/internal/entities"
+	"github.com/lib/pq"
+)
+
+// Postgres NOTIFY channels. Triggers on the chirps and notifications tables
+// publish the ID of each new row on them.
+const (
+	chirpCreatedChannel        = "chirp_created"
+	notificationCreatedChannel = "notification_created"
+)
+
+const (
+	streamBufferSize        = 16
+	streamHeartbeatInterval = 30 * time.Second
+	listenerPingInterval    = 90 * time.Second
+)
+
+// notificationEvent is a new notification along with the user it is for.
+type notificationEvent struct {
+	UserID       uuid.UUID
+	Notification Notification
+}
+
+// broker fans events out to the streams connected to this instance. Every
+// instance listens for notifications itself, so clients see events caused
+// by requests to any of them.
+type broker[T any] struct {
+	mu          sync.Mutex
+	subscribers map[chan T]struct{}
+	closed      bool
+}
+
+func newBroker[T any]() *broker[T] {
+	return &broker[T]{subscribers: map[chan T]struct{}{}}
+}
+
+func (b *broker[T]) subscribe() chan T {
+	ch := make(chan T, streamBufferSize)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	b.subscribers[ch] = struct{}{}
+
+	return ch
+}
+
+func (b *broker[T]) unsubscribe(ch chan T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publish delivers event to every subscriber. A subscriber that has fallen a
+// full buffer behind is dropped and its channel closed rather than holding up
+// everyone else; clients are expected to reconnect and catch up.
+func (b *broker[T]) publish(event T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			slog.Warn("Dropping slow stream subscriber")
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// close ends every subscription, and any made later, so that streams return
+// when the server shuts down.
+func (b *broker[T]) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// isClosed reports whether close has been called, telling a subscriber whose
+// channel was closed whether it was dropped or the server is shutting down.
+func (b *broker[T]) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.closed
+}
+
+// listen loads each row announced on listener and publishes it to the
+// matching broker until ctx is done.
+func (cfg *apiConfig) listen(ctx context.Context, listener *pq.Listener) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			// A nil notification means the connection was re-established;
+			// anything sent while it was down is lost.
+			if n == nil {
+				continue
+			}
+
+			id, err := uuid.Parse(n.Extra)
+			if err != nil {
+				slog.Error("Error parsing ID from notification", "channel", n.Channel, "error", err)
+				continue
+			}
+
+			switch n.Channel {
+			case chirpCreatedChannel:
+				err = cfg.publishChirp(ctx, id)
+			case notificationCreatedChannel:
+				err = cfg.publishNotification(ctx, id)
+			}
+			if err != nil {
+				slog.Error("Error publishing event", "channel", n.Channel, "id", id, "error", err)
+			}
+		case <-time.After(listenerPingInterval):
+			go listener.Ping()
+		}
+	}
+}
+
+func (cfg *apiConfig) publishChirp(ctx context.Context, chirpID uuid.UUID) error {
+	dbChirp, err := cfg.db.GetChirp(ctx, chirpID)
+	if err != nil {
+		return err
+	}
+
+	chirps := []Chirp{chirpFromDB(dbChirp)}
+	if err := cfg.decorateChirps(ctx, uuid.NullUUID{}, chirps); err != nil {
+		return err
+	}
+
+	cfg.chirpStream.publish(chirps[0])
+
+	return nil
+}
+
+func (cfg *apiConfig) publishNotification(ctx context.Context, notificationID uuid.UUID) error {
+	row, err := cfg.db.GetNotification(ctx, notificationID)
+	if err != nil {
+		return err
+	}
+
+	cfg.notificationStream.publish(notificationEvent{
+		UserID:       row.UserID,
+		Notification: notificationFromDB(database.ListNotificationsRow(row)),
+	})
+
+	return nil
+}
+
+// handlerStreamChirps sends newly created Chirps to the client as Server-Sent
+// Events, optionally limited to one author or hashtag.
+func (cfg *apiConfig) handlerStreamChirps(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	authorID, err := parseAuthorID(query)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hashtag := ""
+	if tag := query.Get("hashtag"); tag != "" {
+		var ok bool
+		if hashtag, ok = entities.NormalizeHashtag(tag); !ok {
+			respondWithError(w, http.StatusBadRequest, "Invalid hashtag")
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	if err := clearDeadlines(w); err != nil {
+		logger(req.Context()).Error("Error clearing stream deadlines", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	chirps := cfg.chirpStream.subscribe()
+	defer cfg.chirpStream.unsubscribe(chirps)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case chirp, ok := <-chirps:
+			if !ok {
+				return
+			}
+			if authorID.Valid && chirp.UserID != authorID.UUID {
+				continue
+			}
+			if hashtag != "" && !slices.Contains(entities.Hashtags(chirp.Body), hashtag) {
+				continue
+			}
+
+			data, err := json.Marshal(chirp)
+			if err != nil {
+				logger(req.Context()).Error("Error marshalling JSON", "error", err)
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: chirp\ndata: %s\n\n", chirp.ID, data); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+// clearDeadlines lifts the server's read and write timeouts from a
+// long-lived stream, which would otherwise be cut off once they passed.
+// Streams send heartbeats instead to notice clients that have gone away.
+func clearDeadlines(w http.ResponseWriter) error {
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		return err
+	}
+	return rc.SetWriteDeadline(time.Time{})
+}
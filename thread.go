@@ -0,0 +1,163 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/database"
+)
+
+// ThreadEntry is a Chirp within a thread. Depth is relative to the requested
+// Chirp: negative for the Chirps it replies to, positive for its replies.
+type ThreadEntry struct {
+	Chirp
+	Deleted bool  `json:"deleted"`
+	Depth   int32 `json:"depth"`
+}
+
+type Thread struct {
+	Ancestors  []ThreadEntry `json:"ancestors"`
+	Chirp      ThreadEntry   `json:"chirp"`
+	Replies    []ThreadEntry `json:"replies"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// handlerGetChirpThread returns the chain of Chirps a Chirp replies to, root
+// first, followed by a page of its replies at any depth. Replies are ordered
+// by creation time so a parent always appears before its children.
+func (cfg *apiConfig) handlerGetChirpThread(w http.ResponseWriter, req *http.Request) {
+	chirpID := req.PathValue("chirpID")
+	chirpUUID, err := uuid.Parse(chirpID)
+	if err != nil {
+		logger(req.Context()).Warn("Error parsing Chirp ID", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID")
+		return
+	}
+
+	viewerID, err := cfg.getOptionalUserIDFromRequest(req)
+	if err != nil {
+		logger(req.Context()).Warn("Error authenticating user", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	params := database.GetChirpDescendantsParams{ChirpID: chirpUUID, Limit: p.Limit + 1}
+	params.CursorCreatedAt, params.CursorID = cursorParams(p.Cursor)
+
+	dbChirp, err := cfg.db.GetChirp(req.Context(), chirpUUID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting Chirp from DB", "error", err)
+
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusNotFound, "Chirp not found")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Unknown error getting Chirp")
+		}
+		return
+	}
+
+	ancestors, err := cfg.db.GetChirpAncestors(req.Context(), chirpUUID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting Chirp ancestors from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting thread")
+		return
+	}
+
+	replies, err := cfg.db.GetChirpDescendants(req.Context(), params)
+	if err != nil {
+		logger(req.Context()).Error("Error getting Chirp replies from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting thread")
+		return
+	}
+
+	replies, nextCursor := trimPage(replies, p.Limit, func(reply database.GetChirpDescendantsRow) cursor {
+		return cursor{CreatedAt: reply.CreatedAt, ID: reply.ID}
+	})
+
+	// Decorate every Chirp in the thread in one pass, then split them back
+	// into ancestors, the requested Chirp and replies.
+	chirps := make([]Chirp, 0, len(ancestors)+1+len(replies))
+	deleted := make([]sql.NullTime, 0, cap(chirps))
+	depths := make([]int32, 0, cap(chirps))
+
+	for _, ancestor := range ancestors {
+		chirps = append(chirps, chirpFromDB(database.Chirp{
+			ID:        ancestor.ID,
+			CreatedAt: ancestor.CreatedAt,
+			UpdatedAt: ancestor.UpdatedAt,
+			Body:      ancestor.Body,
+			UserID:    ancestor.UserID,
+			ReplyToID: ancestor.ReplyToID,
+			QuoteOfID: ancestor.QuoteOfID,
+			LikeCount: ancestor.LikeCount,
+		}))
+		deleted = append(deleted, ancestor.DeletedAt)
+		depths = append(depths, -ancestor.Depth)
+	}
+
+	chirps = append(chirps, chirpFromDB(dbChirp))
+	deleted = append(deleted, dbChirp.DeletedAt)
+	depths = append(depths, 0)
+
+	for _, reply := range replies {
+		chirps = append(chirps, chirpFromDB(database.Chirp{
+			ID:        reply.ID,
+			CreatedAt: reply.CreatedAt,
+			UpdatedAt: reply.UpdatedAt,
+			Body:      reply.Body,
+			UserID:    reply.UserID,
+			ReplyToID: reply.ReplyToID,
+			QuoteOfID: reply.QuoteOfID,
+			LikeCount: reply.LikeCount,
+		}))
+		deleted = append(deleted, reply.DeletedAt)
+		depths = append(depths, reply.Depth)
+	}
+
+	if err := cfg.decorateChirps(req.Context(), viewerID, chirps); err != nil {
+		logger(req.Context()).Error("Error decorating Chirps", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting thread")
+		return
+	}
+
+	thread := Thread{Ancestors: []ThreadEntry{}, Replies: []ThreadEntry{}, NextCursor: nextCursor}
+
+	for i, chirp := range chirps {
+		entry := newThreadEntry(chirp, deleted[i], depths[i])
+
+		switch {
+		case i < len(ancestors):
+			thread.Ancestors = append(thread.Ancestors, entry)
+		case i == len(ancestors):
+			thread.Chirp = entry
+		default:
+			thread.Replies = append(thread.Replies, entry)
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, thread)
+}
+
+// newThreadEntry wraps chirp for a thread response. Tombstoned Chirps keep
+// their place in the conversation but do not reveal their author.
+func newThreadEntry(chirp Chirp, deletedAt sql.NullTime, depth int32) ThreadEntry {
+	if deletedAt.Valid {
+		chirp = Chirp{
+			ID:        chirp.ID,
+			ReplyToID: chirp.ReplyToID,
+			Mentions:  []Mention{},
+			Media:     []Media{},
+			CreatedAt: chirp.CreatedAt,
+		}
+	}
+
+	return ThreadEntry{Chirp: chirp, Deleted: deletedAt.Valid, Depth: depth}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/database"
+)
+
+// handlerGetTimeline returns the authenticated user's home timeline: their
+// own Chirps and those of the users they follow, newest first.
+func (cfg *apiConfig) handlerGetTimeline(w http.ResponseWriter, req *http.Request) {
+	userID, err := cfg.getUserIDFromRequest(req)
+	if err != nil {
+		logger(req.Context()).Warn("Error authenticating user", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	p, err := parsePage(req.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	params := database.ListTimelineChirpsParams{UserID: userID, Limit: p.Limit + 1}
+	params.CursorCreatedAt, params.CursorID = cursorParams(p.Cursor)
+
+	dbChirps, err := cfg.db.ListTimelineChirps(req.Context(), params)
+	if err != nil {
+		logger(req.Context()).Error("Error getting timeline from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting timeline")
+		return
+	}
+
+	chirps := Chirps{Entries: []Chirp{}}
+
+	dbChirps, chirps.NextCursor = trimPage(dbChirps, p.Limit, chirpCursor)
+
+	for _, dbChirp := range dbChirps {
+		chirps.Entries = append(chirps.Entries, chirpFromDB(dbChirp))
+	}
+
+	viewerID := uuid.NullUUID{UUID: userID, Valid: true}
+	if err := cfg.decorateChirps(req.Context(), viewerID, chirps.Entries); err != nil {
+		logger(req.Context()).Error("Error decorating Chirps", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting timeline")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+)
+
+const serviceName = "chirpy"
+
+// tracer creates the spans for database queries. Spans for requests come from
+// otelhttp.
+var tracer = otel.Tracer("github.com/keithcrooks/chirpy")
+
+// setupTracing installs the global tracer provider and the W3C trace context
+// propagator. exporter is the value of OTEL_TRACES_EXPORTER:
+//
+//   - "otlp" sends spans over OTLP/HTTP, to the endpoint set by the standard
+//     OTEL_EXPORTER_OTLP_ENDPOINT variables (a local collector by default)
+//   - "console" writes spans to stdout, for tests and debugging
+//   - "none" or empty disables tracing, though incoming trace context is still
+//     propagated
+//
+// The returned function flushes any buffered spans and must be called before
+// exiting.
+func setupTracing(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		spanExporter, err = otlptracehttp.New(ctx)
+	case "console":
+		spanExporter, err = stdouttrace.New()
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults.
+	res, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// middlewareTracing starts a span for every request, continuing the trace
+// from any traceparent header. Spans are named after the route pattern the
+// request matched.
+func middlewareTracing(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, serviceName,
+		otelhttp.WithSpanNameFormatter(func(operation string, req *http.Request) string {
+			if req.Pattern != "" {
+				return req.Pattern
+			}
+			return req.Method
+		}),
+	)
+}
@@ -1,15 +1,17 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/keithcrooks/chirpy/internal/auth"
 	"github.com/keithcrooks/chirpy/internal/database"
+	"github.com/lib/pq"
 )
 
 type User struct {
@@ -17,31 +19,54 @@ type User struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	Email        string    `json:"email"`
+	Username     string    `json:"username,omitempty"`
+	DisplayName  *string   `json:"display_name,omitempty"`
+	Bio          *string   `json:"bio,omitempty"`
+	AvatarURL    *string   `json:"avatar_url,omitempty"`
 	Password     string    `json:"password,omitempty"`
 	Token        string    `json:"token,omitempty"`
 	RefreshToken string    `json:"refresh_token,omitempty"`
+	Role         string    `json:"role,omitempty"`
 	IsChirpyRed  bool      `json:"is_chirpy_red"`
 }
 
 func (cfg *apiConfig) handlerAddUser(w http.ResponseWriter, req *http.Request) {
 	user, err := getUserFromRequest(req)
 	if err != nil {
-		log.Printf("Error getting user from request: %s", err)
+		logger(req.Context()).Warn("Error getting user from request", "error", err)
 		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
 	}
 
 	hashedPassword, err := auth.HashPassword(user.Password)
 	if err != nil {
-		log.Printf("Error hashing password: %s", err)
+		logger(req.Context()).Error("Error hashing password", "error", err)
 		respondWithError(w, http.StatusInternalServerError, "Could not hash password")
 		return
 	}
 
-	params := database.CreateUserParams{Email: user.Email, HashedPassword: hashedPassword}
+	profile, err := parseProfile(user)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	params := database.CreateUserParams{
+		Email:          user.Email,
+		HashedPassword: hashedPassword,
+		Username:       profile.Username,
+		DisplayName:    profile.DisplayName.String,
+		Bio:            profile.Bio.String,
+		AvatarUrl:      profile.AvatarURL.String,
+	}
 	dbUser, err := cfg.db.CreateUser(req.Context(), params)
 	if err != nil {
-		log.Printf("Error creating user: %s", err)
+		logger(req.Context()).Error("Error creating user", "error", err)
+		if isUniqueViolation(err, usernameIndex) {
+			respondWithError(w, http.StatusConflict, "Username is already taken")
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Could not create user")
+		return
 	}
 
 	user.ID = dbUser.ID
@@ -49,6 +74,7 @@ func (cfg *apiConfig) handlerAddUser(w http.ResponseWriter, req *http.Request) {
 	user.UpdatedAt = dbUser.UpdatedAt
 	user.Password = ""
 	user.IsChirpyRed = dbUser.IsChirpyRed
+	setUserProfile(&user, dbUser)
 
 	respondWithJSON(w, http.StatusCreated, user)
 }
@@ -56,14 +82,14 @@ func (cfg *apiConfig) handlerAddUser(w http.ResponseWriter, req *http.Request) {
 func (cfg *apiConfig) handlerLoginUser(w http.ResponseWriter, req *http.Request) {
 	user, err := getUserFromRequest(req)
 	if err != nil {
-		log.Printf("Error getting user from request: %s", err)
+		logger(req.Context()).Warn("Error getting user from request", "error", err)
 		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
 		return
 	}
 
 	dbUser, err := cfg.db.GetUserByEmail(req.Context(), user.Email)
 	if err != nil {
-		log.Printf("Error getting user from the database: %s", err)
+		logger(req.Context()).Error("Error getting user from the database", "error", err)
 		switch err {
 		case sql.ErrNoRows:
 			respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
@@ -79,7 +105,7 @@ func (cfg *apiConfig) handlerLoginUser(w http.ResponseWriter, req *http.Request)
 
 	passwordOk, err := auth.CheckPasswordHash(user.Password, dbUser.HashedPassword)
 	if err != nil {
-		log.Printf("Error checking password: %s", err)
+		logger(req.Context()).Error("Error checking password", "error", err)
 		respondWithError(
 			w,
 			http.StatusInternalServerError,
@@ -93,7 +119,12 @@ func (cfg *apiConfig) handlerLoginUser(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	token, err := auth.MakeJWT(dbUser.ID, cfg.tokenSecret, time.Hour)
+	if dbUser.SuspendedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Account is suspended")
+		return
+	}
+
+	token, err := auth.MakeJWTWithRole(dbUser.ID, dbUser.Role, cfg.tokenSecret, time.Hour)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating auth token")
 		return
@@ -113,7 +144,9 @@ func (cfg *apiConfig) handlerLoginUser(w http.ResponseWriter, req *http.Request)
 	user.Password = ""
 	user.Token = token
 	user.RefreshToken = refreshToken
+	user.Role = dbUser.Role
 	user.IsChirpyRed = dbUser.IsChirpyRed
+	setUserProfile(&user, dbUser)
 
 	respondWithJSON(w, http.StatusOK, user)
 }
@@ -121,7 +154,7 @@ func (cfg *apiConfig) handlerLoginUser(w http.ResponseWriter, req *http.Request)
 func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, req *http.Request) {
 	token, err := auth.GetBearerToken(req.Header)
 	if err != nil {
-		log.Printf("Error getting getting bearer token: %v", err)
+		logger(req.Context()).Warn("Error getting bearer token", "error", err)
 		respondWithError(w, http.StatusBadRequest, "invalid token")
 	}
 
@@ -135,7 +168,15 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	authToken, err := auth.MakeJWT(refreshToken.UserID, cfg.tokenSecret, time.Hour)
+	// The role is looked up again so that changes to it reach new tokens.
+	dbUser, err := cfg.db.GetUser(req.Context(), refreshToken.UserID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting user from the database", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Error creating auth token")
+		return
+	}
+
+	authToken, err := auth.MakeJWTWithRole(dbUser.ID, dbUser.Role, cfg.tokenSecret, time.Hour)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating auth token")
 		return
@@ -147,12 +188,12 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, req *http.Request) {
 func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, req *http.Request) {
 	token, err := auth.GetBearerToken(req.Header)
 	if err != nil {
-		log.Printf("Error getting getting bearer token: %v", err)
+		logger(req.Context()).Warn("Error getting bearer token", "error", err)
 		respondWithError(w, http.StatusBadRequest, "invalid token")
 	}
 
 	if err := cfg.db.RevokeRefreshToken(req.Context(), token); err != nil {
-		log.Printf("Error revoking refresh token: %v", err)
+		logger(req.Context()).Error("Error revoking refresh token", "error", err)
 		respondWithError(
 			w,
 			http.StatusInternalServerError,
@@ -166,7 +207,7 @@ func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, req *http.Request) {
 func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, req *http.Request) {
 	token, err := auth.GetBearerToken(req.Header)
 	if err != nil {
-		log.Printf("Error getting getting bearer token: %v", err)
+		logger(req.Context()).Warn("Error getting bearer token", "error", err)
 		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
 		return
 	}
@@ -176,24 +217,44 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, req *http.Request
 		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
 		return
 	}
+	setRequestUser(req.Context(), userID)
+
+	if !cfg.requireActiveUser(w, req, userID) {
+		return
+	}
 
 	user, err := getUserFromRequest(req)
 	if err != nil {
-		log.Printf("Error getting user from request: %s", err)
+		logger(req.Context()).Warn("Error getting user from request", "error", err)
 		respondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
 		return
 	}
 
 	hashedPassword, err := auth.HashPassword(user.Password)
 	if err != nil {
-		log.Printf("Error hashing password: %s", err)
+		logger(req.Context()).Error("Error hashing password", "error", err)
 		respondWithError(w, http.StatusInternalServerError, "Could not hash password")
 		return
 	}
 
-	params := database.UpdateUserEmailAndPasswordParams{Email: user.Email, HashedPassword: hashedPassword, ID: userID}
-	dbUser, err := cfg.db.UpdateUserEmailAndPassword(req.Context(), params)
+	profile, err := parseProfile(user)
 	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	params := database.UpdateUserEmailAndPasswordParams{
+		Email:          user.Email,
+		HashedPassword: hashedPassword,
+		ID:             userID,
+	}
+	dbUser, err := cfg.updateUser(req.Context(), params, profile)
+	if err != nil {
+		logger(req.Context()).Error("Error updating user", "error", err)
+		if isUniqueViolation(err, usernameIndex) {
+			respondWithError(w, http.StatusConflict, "Username is already taken")
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Error updating user record")
 		return
 	}
@@ -201,10 +262,73 @@ func (cfg *apiConfig) handlerUpdateUser(w http.ResponseWriter, req *http.Request
 	user.ID = dbUser.ID
 	user.CreatedAt = dbUser.CreatedAt
 	user.UpdatedAt = dbUser.UpdatedAt
+	user.Password = ""
+	user.IsChirpyRed = dbUser.IsChirpyRed
+	setUserProfile(&user, dbUser)
 
 	respondWithJSON(w, http.StatusOK, user)
 }
 
+// Unique constraints on users. Usernames are unique regardless of case.
+const (
+	emailIndex    = "users_email_key"
+	usernameIndex = "users_username_lower_idx"
+)
+
+// updateUser changes a user's credentials and profile in one transaction.
+func (cfg *apiConfig) updateUser(
+	ctx context.Context,
+	params database.UpdateUserEmailAndPasswordParams,
+	profile profileParams,
+) (database.User, error) {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.User{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.withTx(tx)
+
+	if _, err := qtx.UpdateUserEmailAndPassword(ctx, params); err != nil {
+		return database.User{}, err
+	}
+
+	dbUser, err := qtx.UpdateUserProfile(ctx, database.UpdateUserProfileParams{
+		Username:    profile.Username,
+		DisplayName: profile.DisplayName,
+		Bio:         profile.Bio,
+		AvatarUrl:   profile.AvatarURL,
+		ID:          params.ID,
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return dbUser, tx.Commit()
+}
+
+// setUserProfile copies the profile fields of dbUser into user.
+func setUserProfile(user *User, dbUser database.User) {
+	user.Username = dbUser.Username.String
+	user.DisplayName = &dbUser.DisplayName
+	user.Bio = &dbUser.Bio
+	user.AvatarURL = &dbUser.AvatarUrl
+}
+
+// isUniqueViolation reports whether err was caused by a duplicate value in
+// the named unique constraint or index.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+// isForeignKeyViolation reports whether err was caused by a reference, via
+// the named foreign key, to a row that does not exist.
+func isForeignKeyViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503" && pqErr.Constraint == constraint
+}
+
 func getUserFromRequest(req *http.Request) (User, error) {
 	var user User
 
@@ -214,3 +338,79 @@ func getUserFromRequest(req *http.Request) (User, error) {
 
 	return user, err
 }
+
+// getUserIDFromRequest authenticates the request's bearer token and returns
+// the ID of the user it was issued to.
+func (cfg *apiConfig) getUserIDFromRequest(req *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	setRequestUser(req.Context(), userID)
+
+	return userID, nil
+}
+
+// getActiveUserIDFromRequest is like getUserIDFromRequest but also rejects
+// suspended users, for requests that change anything. It writes an error
+// response and returns false on failure.
+func (cfg *apiConfig) getActiveUserIDFromRequest(w http.ResponseWriter, req *http.Request) (uuid.UUID, bool) {
+	userID, err := cfg.getUserIDFromRequest(req)
+	if err != nil {
+		logger(req.Context()).Warn("Error authenticating user", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return uuid.UUID{}, false
+	}
+
+	if !cfg.requireActiveUser(w, req, userID) {
+		return uuid.UUID{}, false
+	}
+
+	return userID, true
+}
+
+// requireActiveUser checks that an authenticated user has not been suspended.
+// Suspension revokes refresh tokens, but an access token issued before it
+// stays valid until it expires, so every authenticated write checks. It
+// writes an error response and returns false on failure.
+func (cfg *apiConfig) requireActiveUser(w http.ResponseWriter, req *http.Request, userID uuid.UUID) bool {
+	dbUser, err := cfg.db.GetUser(req.Context(), userID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting user from DB", "error", err)
+
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Unknown error getting user")
+		}
+		return false
+	}
+
+	if dbUser.SuspendedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Account is suspended")
+		return false
+	}
+
+	return true
+}
+
+// getOptionalUserIDFromRequest is like getUserIDFromRequest but allows
+// anonymous requests, for which it returns an invalid NullUUID.
+func (cfg *apiConfig) getOptionalUserIDFromRequest(req *http.Request) (uuid.NullUUID, error) {
+	if req.Header.Get("Authorization") == "" {
+		return uuid.NullUUID{}, nil
+	}
+
+	userID, err := cfg.getUserIDFromRequest(req)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+
+	return uuid.NullUUID{UUID: userID, Valid: true}, nil
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/database"
+	"github.com/keithcrooks/chirpy/internal/moderation"
+)
+
+const (
+	maxWordListNameLength = 64
+	maxWordListWords      = 1000
+)
+
+// wordListNameIndex is the unique constraint on word list names.
+const wordListNameIndex = "word_lists_name_key"
+
+// WordList is a moderation word list. Every word in it gets the list's
+// action: mask, reject or flag.
+type WordList struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Action    string    `json:"action"`
+	Words     []string  `json:"words"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (cfg *apiConfig) handlerGetWordLists(w http.ResponseWriter, req *http.Request) {
+	dbLists, err := cfg.db.ListWordLists(req.Context())
+	if err != nil {
+		logger(req.Context()).Error("Error getting word lists from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting word lists")
+		return
+	}
+
+	lists, err := cfg.wordListsFromDB(req.Context(), dbLists)
+	if err != nil {
+		logger(req.Context()).Error("Error getting word list words from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting word lists")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, lists)
+}
+
+func (cfg *apiConfig) handlerGetWordList(w http.ResponseWriter, req *http.Request) {
+	dbList, ok := cfg.getWordListFromPath(w, req)
+	if !ok {
+		return
+	}
+
+	lists, err := cfg.wordListsFromDB(req.Context(), []database.WordList{dbList})
+	if err != nil {
+		logger(req.Context()).Error("Error getting word list words from DB", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error getting word list")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, lists[0])
+}
+
+func (cfg *apiConfig) handlerCreateWordList(w http.ResponseWriter, req *http.Request) {
+	list, err := getWordListFromRequest(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dbList, err := cfg.saveWordList(req.Context(), uuid.NullUUID{}, list)
+	if err != nil {
+		logger(req.Context()).Error("Error creating word list", "error", err)
+		if isUniqueViolation(err, wordListNameIndex) {
+			respondWithError(w, http.StatusConflict, "Word list name is already taken")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error creating word list")
+		return
+	}
+
+	list.ID = dbList.ID
+	list.CreatedAt = dbList.CreatedAt
+	list.UpdatedAt = dbList.UpdatedAt
+
+	respondWithJSON(w, http.StatusCreated, list)
+}
+
+// handlerUpdateWordList replaces a list's name, action and words.
+func (cfg *apiConfig) handlerUpdateWordList(w http.ResponseWriter, req *http.Request) {
+	dbList, ok := cfg.getWordListFromPath(w, req)
+	if !ok {
+		return
+	}
+
+	list, err := getWordListFromRequest(req)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dbList, err = cfg.saveWordList(req.Context(), uuid.NullUUID{UUID: dbList.ID, Valid: true}, list)
+	if err != nil {
+		logger(req.Context()).Error("Error updating word list", "error", err)
+		if isUniqueViolation(err, wordListNameIndex) {
+			respondWithError(w, http.StatusConflict, "Word list name is already taken")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error updating word list")
+		return
+	}
+
+	list.ID = dbList.ID
+	list.CreatedAt = dbList.CreatedAt
+	list.UpdatedAt = dbList.UpdatedAt
+
+	respondWithJSON(w, http.StatusOK, list)
+}
+
+func (cfg *apiConfig) handlerDeleteWordList(w http.ResponseWriter, req *http.Request) {
+	listID, err := uuid.Parse(req.PathValue("listID"))
+	if err != nil {
+		logger(req.Context()).Warn("Error parsing word list ID", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid word list ID")
+		return
+	}
+
+	deleted, err := cfg.db.DeleteWordList(req.Context(), listID)
+	if err != nil {
+		logger(req.Context()).Error("Error deleting word list", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Error deleting word list")
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Word list not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// getWordListFromPath looks up the word list named by the {listID} path
+// value. It writes an error response and returns false on failure.
+func (cfg *apiConfig) getWordListFromPath(w http.ResponseWriter, req *http.Request) (database.WordList, bool) {
+	listID, err := uuid.Parse(req.PathValue("listID"))
+	if err != nil {
+		logger(req.Context()).Warn("Error parsing word list ID", "error", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid word list ID")
+		return database.WordList{}, false
+	}
+
+	dbList, err := cfg.db.GetWordList(req.Context(), listID)
+	if err != nil {
+		logger(req.Context()).Error("Error getting word list from DB", "error", err)
+
+		switch err {
+		case sql.ErrNoRows:
+			respondWithError(w, http.StatusNotFound, "Word list not found")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Unknown error getting word list")
+		}
+		return database.WordList{}, false
+	}
+
+	return dbList, true
+}
+
+// getWordListFromRequest decodes and validates a word list. Words are
+// trimmed, lowercased and de-duplicated.
+func getWordListFromRequest(req *http.Request) (WordList, error) {
+	var list WordList
+	if err := json.NewDecoder(req.Body).Decode(&list); err != nil {
+		logger(req.Context()).Warn("Error decoding word list", "error", err)
+		return WordList{}, errors.New("Could not read word list")
+	}
+
+	list.Name = strings.TrimSpace(list.Name)
+	if list.Name == "" || len(list.Name) > maxWordListNameLength {
+		return WordList{}, errors.New("Word list name must be 1-64 characters")
+	}
+
+	if !moderation.Action(list.Action).IsValid() {
+		return WordList{}, errors.New("Action must be mask, reject or flag")
+	}
+
+	if len(list.Words) > maxWordListWords {
+		return WordList{}, errors.New("Word list is too long")
+	}
+
+	seen := map[string]bool{}
+	words := []string{}
+	for _, word := range list.Words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if moderation.Normalize(word) == "" || strings.ContainsFunc(word, isSpace) {
+			return WordList{}, errors.New("Words must be single words containing letters")
+		}
+
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	list.Words = words
+
+	return list, nil
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// saveWordList creates list, or replaces the list with listID if it is
+// valid, along with its words in a single transaction.
+func (cfg *apiConfig) saveWordList(ctx context.Context, listID uuid.NullUUID, list WordList) (database.WordList, error) {
+	tx, err := cfg.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.WordList{}, err
+	}
+	defer tx.Rollback()
+
+	qtx := cfg.withTx(tx)
+
+	var dbList database.WordList
+	if listID.Valid {
+		params := database.UpdateWordListParams{Name: list.Name, Action: list.Action, ID: listID.UUID}
+		dbList, err = qtx.UpdateWordList(ctx, params)
+		if err != nil {
+			return database.WordList{}, err
+		}
+
+		if err := qtx.DeleteWordListWords(ctx, dbList.ID); err != nil {
+			return database.WordList{}, err
+		}
+	} else {
+		params := database.CreateWordListParams{Name: list.Name, Action: list.Action}
+		dbList, err = qtx.CreateWordList(ctx, params)
+		if err != nil {
+			return database.WordList{}, err
+		}
+	}
+
+	for _, word := range list.Words {
+		params := database.CreateWordListWordParams{WordListID: dbList.ID, Word: word}
+		if err := qtx.CreateWordListWord(ctx, params); err != nil {
+			return database.WordList{}, err
+		}
+	}
+
+	return dbList, tx.Commit()
+}
+
+// wordListsFromDB converts dbLists to their JSON form, loading all of their
+// words with a single query.
+func (cfg *apiConfig) wordListsFromDB(ctx context.Context, dbLists []database.WordList) ([]WordList, error) {
+	lists := make([]WordList, 0, len(dbLists))
+	if len(dbLists) == 0 {
+		return lists, nil
+	}
+
+	listIDs := make([]uuid.UUID, 0, len(dbLists))
+	for _, dbList := range dbLists {
+		listIDs = append(listIDs, dbList.ID)
+	}
+
+	rows, err := cfg.db.GetWordListWords(ctx, listIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	words := make(map[uuid.UUID][]string, len(dbLists))
+	for _, row := range rows {
+		words[row.WordListID] = append(words[row.WordListID], row.Word)
+	}
+
+	for _, dbList := range dbLists {
+		list := WordList{
+			ID:        dbList.ID,
+			Name:      dbList.Name,
+			Action:    dbList.Action,
+			Words:     []string{},
+			CreatedAt: dbList.CreatedAt,
+			UpdatedAt: dbList.UpdatedAt,
+		}
+		if w, ok := words[dbList.ID]; ok {
+			list.Words = w
+		}
+
+		lists = append(lists, list)
+	}
+
+	return lists, nil
+}
+
+// moderateChirp checks body against the word lists in the database. The
+// lists are read on every call so edits take effect at once on all servers.
+func (cfg *apiConfig) moderateChirp(ctx context.Context, body string) (moderation.Result, error) {
+	rows, err := cfg.db.ListModerationWords(ctx)
+	if err != nil {
+		return moderation.Result{}, err
+	}
+
+	lists := []moderation.List{}
+	for _, row := range rows {
+		if len(lists) == 0 || lists[len(lists)-1].Name != row.Name {
+			lists = append(lists, moderation.List{Name: row.Name, Action: moderation.Action(row.Action)})
+		}
+
+		last := &lists[len(lists)-1]
+		last.Words = append(last.Words, row.Word)
+	}
+
+	return moderation.NewFilter(lists).Check(body), nil
+}
+
+// moderateChirpBody checks a Chirp body on behalf of a handler. It writes an
+// error response and returns false if the body is rejected or cannot be
+// checked.
+func (cfg *apiConfig) moderateChirpBody(w http.ResponseWriter, req *http.Request, body string) (moderation.Result, bool) {
+	result, err := cfg.moderateChirp(req.Context(), body)
+	if err != nil {
+		logger(req.Context()).Error("Error moderating Chirp", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Unknown error checking Chirp")
+		return moderation.Result{}, false
+	}
+
+	if result.Rejected {
+		respondWithError(w, http.StatusBadRequest, "Chirp contains prohibited language")
+		return moderation.Result{}, false
+	}
+
+	return result, true
+}
+
+// saveChirpFlags records the matches that flag a Chirp and raises a report
+// so a moderator reviews it. It is run with transactional queries alongside
+// the write that produced the body.
+func saveChirpFlags(ctx context.Context, qtx *database.Queries, dbChirp database.Chirp, matches []moderation.Match) error {
+	for _, match := range matches {
+		if match.Action != moderation.Flag {
+			continue
+		}
+
+		params := database.CreateChirpFlagParams{ChirpID: dbChirp.ID, ListName: match.List, Word: match.Word}
+		if err := qtx.CreateChirpFlag(ctx, params); err != nil {
+			return err
+		}
+	}
+
+	return reportChirpFlags(ctx, qtx, dbChirp, matches)
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/google/uuid"
+	"github.com/keithcrooks/chirpy/internal/auth"
+)
+
+// WebSocket channels a client can subscribe to. A user's Chirps are
+// subscribed to as "user:" followed by their ID.
+const (
+	wsChannelTimeline      = "timeline"
+	wsChannelNotifications = "notifications"
+	wsChannelUserPrefix    = "user:"
+)
+
+const wsWriteTimeout = 10 * time.Second
+
+// wsClientMessage is sent by the client to change its subscriptions or to
+// swap in a fresh token before the current one expires.
+type wsClientMessage struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+// wsServerMessage is sent to the client. Data holds a Chirp or Notification
+// for events, and Error describes why a client message was rejected.
+type wsServerMessage struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel,omitempty"`
+	Data    any    `json:"data,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// wsSession is the state of one WebSocket connection.
+type wsSession struct {
+	cfg       *apiConfig
+	conn      *websocket.Conn
+	userID    uuid.UUID
+	expiresAt time.Time
+	channels  map[string]bool
+	following map[uuid.UUID]bool
+}
+
+// handlerWebSocket upgrades the request to a WebSocket over which the client
+// receives live Chirps and notifications for the channels it subscribes to.
+// Browsers cannot set headers on WebSocket requests, so the JWT may also be
+// passed in the token query parameter.
+func (cfg *apiConfig) handlerWebSocket(w http.ResponseWriter, req *http.Request) {
+	token, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		token = req.URL.Query().Get("token")
+	}
+
+	userID, expiresAt, err := auth.ValidateJWTWithExpiry(token, cfg.tokenSecret)
+	if err != nil {
+		logger(req.Context()).Warn("Error authenticating user", "error", err)
+		respondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+	setRequestUser(req.Context(), userID)
+
+	if !cfg.requireActiveUser(w, req, userID) {
+		return
+	}
+
+	if err := clearDeadlines(w); err != nil {
+		logger(req.Context()).Error("Error clearing WebSocket deadlines", "error", err)
+		respondWithError(w, http.StatusInternalServerError, "Error accepting WebSocket")
+		return
+	}
+
+	conn, err := websocket.Accept(w, req, nil)
+	if err != nil {
+		logger(req.Context()).Error("Error accepting WebSocket", "error", err)
+		return
+	}
+	defer conn.CloseNow()
+
+	s := &wsSession{
+		cfg:       cfg,
+		conn:      conn,
+		userID:    userID,
+		expiresAt: expiresAt,
+		channels:  map[string]bool{},
+		following: map[uuid.UUID]bool{},
+	}
+
+	if err := s.run(req.Context()); err != nil && websocket.CloseStatus(err) == -1 {
+		logger(req.Context()).Error("Error serving WebSocket", "error", err)
+	}
+}
+
+// run serves the connection until the client goes away, stops answering
+// pings, falls too far behind on events or lets its token expire, or the
+// server shuts down.
+func (s *wsSession) run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chirps := s.cfg.chirpStream.subscribe()
+	defer s.cfg.chirpStream.unsubscribe(chirps)
+
+	notifications := s.cfg.notificationStream.subscribe()
+	defer s.cfg.notificationStream.unsubscribe(notifications)
+
+	messages := make(chan wsClientMessage)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			var msg wsClientMessage
+			if err := wsjson.Read(ctx, s.conn, &msg); err != nil {
+				readErr <- err
+				return
+			}
+
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	expiry := time.NewTimer(time.Until(s.expiresAt))
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-readErr:
+			return err
+		case <-expiry.C:
+			return s.conn.Close(websocket.StatusPolicyViolation, "token expired")
+		case <-heartbeat.C:
+			pingCtx, cancelPing := context.WithTimeout(ctx, wsWriteTimeout)
+			err := s.conn.Ping(pingCtx)
+			cancelPing()
+			if err != nil {
+				return err
+			}
+
+			if s.channels[wsChannelTimeline] {
+				if err := s.loadFollowing(ctx); err != nil {
+					return err
+				}
+			}
+		case msg := <-messages:
+			if err := s.handleMessage(ctx, msg); err != nil {
+				return err
+			}
+			expiry.Reset(time.Until(s.expiresAt))
+		case chirp, ok := <-chirps:
+			if !ok {
+				return s.closeUnsubscribed(s.cfg.chirpStream.isClosed())
+			}
+			for _, channel := range s.chirpChannels(chirp) {
+				if err := s.write(ctx, wsServerMessage{Type: "chirp", Channel: channel, Data: chirp}); err != nil {
+					return err
+				}
+			}
+		case event, ok := <-notifications:
+			if !ok {
+				return s.closeUnsubscribed(s.cfg.notificationStream.isClosed())
+			}
+			if event.UserID != s.userID || !s.channels[wsChannelNotifications] {
+				continue
+			}
+			msg := wsServerMessage{Type: "notification", Channel: wsChannelNotifications, Data: event.Notification}
+			if err := s.write(ctx, msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// closeUnsubscribed closes the connection after the session lost its
+// subscription to a broker, either because the server is shutting down or
+// because the client fell too far behind.
+func (s *wsSession) closeUnsubscribed(shuttingDown bool) error {
+	if shuttingDown {
+		return s.conn.Close(websocket.StatusGoingAway, "server shutting down")
+	}
+	return s.conn.Close(websocket.StatusTryAgainLater, "too slow")
+}
+
+// handleMessage applies a client message. Invalid requests are answered with
+// an error message rather than closing the connection.
+func (s *wsSession) handleMessage(ctx context.Context, msg wsClientMessage) error {
+	switch msg.Type {
+	case "subscribe":
+		if !isValidWSChannel(msg.Channel) {
+			return s.write(ctx, wsServerMessage{Type: "error", Channel: msg.Channel, Error: "Invalid channel"})
+		}
+
+		if msg.Channel == wsChannelTimeline {
+			if err := s.loadFollowing(ctx); err != nil {
+				return err
+			}
+		}
+
+		s.channels[msg.Channel] = true
+		return s.write(ctx, wsServerMessage{Type: "subscribed", Channel: msg.Channel})
+	case "unsubscribe":
+		delete(s.channels, msg.Channel)
+		return s.write(ctx, wsServerMessage{Type: "unsubscribed", Channel: msg.Channel})
+	case "auth":
+		userID, expiresAt, err := auth.ValidateJWTWithExpiry(msg.Token, s.cfg.tokenSecret)
+		if err != nil || userID != s.userID {
+			return s.write(ctx, wsServerMessage{Type: "error", Error: "Invalid token"})
+		}
+
+		s.expiresAt = expiresAt
+		return s.write(ctx, wsServerMessage{Type: "authenticated"})
+	default:
+		return s.write(ctx, wsServerMessage{Type: "error", Error: "Unknown message type"})
+	}
+}
+
+// chirpChannels returns the subscribed channels that chirp belongs on.
+func (s *wsSession) chirpChannels(chirp Chirp) []string {
+	channels := []string{}
+
+	if s.channels[wsChannelTimeline] && (chirp.UserID == s.userID || s.following[chirp.UserID]) {
+		channels = append(channels, wsChannelTimeline)
+	}
+
+	if channel := wsChannelUserPrefix + chirp.UserID.String(); s.channels[channel] {
+		channels = append(channels, channel)
+	}
+
+	return channels
+}
+
+// loadFollowing refreshes the set of users whose Chirps belong on the home
+// timeline. It runs on subscribe and with each heartbeat, so follows made
+// while connected show up within one heartbeat interval.
+func (s *wsSession) loadFollowing(ctx context.Context) error {
+	followeeIDs, err := s.cfg.db.ListFollowingIDs(ctx, s.userID)
+	if err != nil {
+		return err
+	}
+
+	s.following = make(map[uuid.UUID]bool, len(followeeIDs))
+	for _, id := range followeeIDs {
+		s.following[id] = true
+	}
+
+	return nil
+}
+
+// write sends msg, giving up on clients that cannot keep up.
+func (s *wsSession) write(ctx context.Context, msg wsServerMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+	defer cancel()
+
+	return wsjson.Write(ctx, s.conn, msg)
+}
+
+func isValidWSChannel(channel string) bool {
+	if channel == wsChannelTimeline || channel == wsChannelNotifications {
+		return true
+	}
+
+	userID, ok := strings.CutPrefix(channel, wsChannelUserPrefix)
+	if !ok {
+		return false
+	}
+
+	_, err := uuid.Parse(userID)
+	return err == nil
+}